@@ -0,0 +1,93 @@
+package accounting
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// Record is the usage of a single upstream call. Estimated is set when the
+// provider never reported usage (e.g. the stream was cut short) and the
+// token counts were derived from the request and the text seen so far.
+// Response is reassembled from the chunks when the call was streamed.
+type Record struct {
+	RequestID string
+	Provider  string
+	Model     string
+	Stream    bool
+	Usage     models.Usage
+	Cost      float64
+	Estimated bool
+	Request   *models.ChatCompletionRequest
+	Response  *models.ChatCompletionResponse
+	Latency   time.Duration
+	Time      time.Time
+}
+
+type Recorder interface {
+	Record(ctx context.Context, rec Record)
+}
+
+type Totals struct {
+	Requests int64
+	Usage    models.Usage
+	Cost     float64
+}
+
+// Ledger keeps running usage totals per provider and model in memory.
+// Shadow calls are totalled apart from client traffic.
+type Ledger struct {
+	mu     sync.Mutex
+	totals map[string]*Totals
+}
+
+func NewLedger() *Ledger {
+	return &Ledger{totals: make(map[string]*Totals)}
+}
+
+func (l *Ledger) Record(ctx context.Context, rec Record) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := rec.Provider + "/" + rec.Model
+	if meta, _ := requestmeta.Lookup(ctx); meta.Route == requestmeta.ShadowRoute {
+		key = requestmeta.ShadowRoute + "/" + key
+	}
+	t, ok := l.totals[key]
+	if !ok {
+		t = &Totals{}
+		l.totals[key] = t
+	}
+	t.Requests++
+	t.Usage.PromptTokens += rec.Usage.PromptTokens
+	t.Usage.CompletionTokens += rec.Usage.CompletionTokens
+	t.Usage.TotalTokens += rec.Usage.TotalTokens
+	t.Usage.CacheReadTokens += rec.Usage.CacheReadTokens
+	t.Usage.CacheWriteTokens += rec.Usage.CacheWriteTokens
+	t.Cost += rec.Cost
+}
+
+// Totals returns a snapshot keyed by "provider/model", or
+// "shadow/provider/model" for shadow calls.
+func (l *Ledger) Totals() map[string]Totals {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	snapshot := make(map[string]Totals, len(l.totals))
+	for k, t := range l.totals {
+		snapshot[k] = *t
+	}
+	return snapshot
+}
+
+// Multi fans a record out to several recorders.
+type Multi []Recorder
+
+func (m Multi) Record(ctx context.Context, rec Record) {
+	for _, r := range m {
+		r.Record(ctx, rec)
+	}
+}
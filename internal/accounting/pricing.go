@@ -0,0 +1,78 @@
+package accounting
+
+import (
+	"strings"
+
+	"github.com/llm-router/internal/models"
+)
+
+// Price is the list price in USD per million tokens. CacheRead and
+// CacheWrite price prompt tokens served from or written to the provider's
+// prompt cache; zero means the provider does not bill them separately.
+type Price struct {
+	Input      float64
+	Output     float64
+	CacheRead  float64
+	CacheWrite float64
+}
+
+// prices is matched by longest model-name prefix so dated snapshots such as
+// "gpt-4o-2024-08-06" pick up their family's price.
+var prices = map[string]Price{
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60, CacheRead: 0.075},
+	"gpt-4o":            {Input: 2.50, Output: 10.00, CacheRead: 1.25},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40, CacheRead: 0.025},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60, CacheRead: 0.10},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00, CacheRead: 0.50},
+	"gpt-4-turbo":       {Input: 10.00, Output: 30.00},
+	"gpt-4":             {Input: 30.00, Output: 60.00},
+	"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25, CacheRead: 0.03, CacheWrite: 0.30},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00, CacheRead: 0.08, CacheWrite: 1.00},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00, CacheRead: 0.30, CacheWrite: 3.75},
+	"claude-3-7-sonnet": {Input: 3.00, Output: 15.00, CacheRead: 0.30, CacheWrite: 3.75},
+	"claude-sonnet-4":   {Input: 3.00, Output: 15.00, CacheRead: 0.30, CacheWrite: 3.75},
+	"claude-3-opus":     {Input: 15.00, Output: 75.00, CacheRead: 1.50, CacheWrite: 18.75},
+	"claude-opus-4":     {Input: 15.00, Output: 75.00, CacheRead: 1.50, CacheWrite: 18.75},
+}
+
+func PriceFor(model string) (Price, bool) {
+	model = strings.ToLower(model)
+	var best string
+	for prefix := range prices {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return Price{}, false
+	}
+	return prices[best], true
+}
+
+// Cost returns the USD cost of usage on model, or 0 for unknown models.
+// Cached prompt tokens are billed at the cache rates where the model has
+// them and at the input rate otherwise.
+func Cost(model string, usage models.Usage) float64 {
+	price, ok := PriceFor(model)
+	if !ok {
+		return 0
+	}
+
+	cacheRead, cacheWrite := price.CacheRead, price.CacheWrite
+	if cacheRead == 0 {
+		cacheRead = price.Input
+	}
+	if cacheWrite == 0 {
+		cacheWrite = price.Input
+	}
+
+	uncached := usage.PromptTokens - usage.CacheReadTokens - usage.CacheWriteTokens
+	if uncached < 0 {
+		uncached = 0
+	}
+	return (float64(uncached)*price.Input +
+		float64(usage.CacheReadTokens)*cacheRead +
+		float64(usage.CacheWriteTokens)*cacheWrite +
+		float64(usage.CompletionTokens)*price.Output) / 1e6
+}
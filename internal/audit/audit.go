@@ -0,0 +1,348 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/llm-router/internal/accounting"
+	"github.com/llm-router/internal/logging"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+const (
+	batchSize     = 100
+	flushInterval = time.Second
+)
+
+// Redaction modes applied to payloads before they reach a sink.
+const (
+	RedactNone    = "none"
+	RedactSecrets = "secrets"
+	RedactContent = "content"
+)
+
+// Sources of the reply an entry describes.
+const (
+	SourceUpstream      = "upstream"
+	SourceCache         = "cache"
+	SourceSemanticCache = "semantic_cache"
+	SourceCoalesced     = "coalesced"
+	SourceFiltered      = "filtered"
+)
+
+// Entry describes one reply to a client. Usage and Cost are those of the
+// upstream calls made for it, so a reply served from a cache costs nothing;
+// Provider lists the providers called.
+type Entry struct {
+	Time           time.Time    `json:"time"`
+	RequestID      string       `json:"request_id"`
+	Route          string       `json:"route"`
+	Key            string       `json:"key"`
+	Source         string       `json:"source"`
+	Provider       string       `json:"provider"`
+	Model          string       `json:"model"`
+	Stream         bool         `json:"stream"`
+	LatencyMS      int64        `json:"latency_ms"`
+	Usage          models.Usage `json:"usage"`
+	EstimatedUsage bool         `json:"estimated_usage,omitempty"`
+	Cost           float64      `json:"cost_usd"`
+	// Request and Response are marshalled when the call is recorded, so
+	// the worker never reads structures the request path may still use.
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// Sink persists batches of entries. Write is only ever called from the
+// logger's single worker goroutine.
+type Sink interface {
+	Write(entries []Entry) error
+	Close() error
+}
+
+type Options struct {
+	SampleRate float64
+	Redact     string
+	BufferSize int
+}
+
+// Logger writes an entry per client reply to a Sink off the request path.
+// Entries are dropped rather than blocking a request when the buffer is full.
+type Logger struct {
+	sink    Sink
+	opts    Options
+	logger  *slog.Logger
+	entries chan Entry
+	dropped atomic.Int64
+
+	// mu guards closed so Record never sends on a closed channel; calls
+	// can still arrive from streams and detached work after Close.
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func NewLogger(sink Sink, opts Options, logger *slog.Logger) *Logger {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+	l := &Logger{
+		sink:    sink,
+		opts:    opts,
+		logger:  logger,
+		entries: make(chan Entry, opts.BufferSize),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// trace collects the upstream calls made while answering one client
+// request so they can be folded into its entry.
+type trace struct {
+	sampled bool
+	mu      sync.Mutex
+	done    bool
+	calls   []accounting.Record
+}
+
+type traceKey struct{}
+
+// add keeps rec for the reply's entry unless that has been written already.
+func (t *trace) add(rec accounting.Record) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return false
+	}
+	t.calls = append(t.calls, rec)
+	return true
+}
+
+func (t *trace) finish() []accounting.Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = true
+	return t.calls
+}
+
+// Reply is the answer a client received.
+type Reply struct {
+	Source   string
+	Stream   bool
+	Request  *models.ChatCompletionRequest
+	Response *models.ChatCompletionResponse
+	Latency  time.Duration
+}
+
+// Begin starts the entry for one client request. Upstream calls recorded
+// under the returned context are held until Finish writes it.
+func (l *Logger) Begin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, traceKey{}, &trace{sampled: l.sample()})
+}
+
+// Finish writes the entry for the request begun on ctx. A failed request
+// (nil Response) is only logged if upstream calls were made for it.
+func (l *Logger) Finish(ctx context.Context, reply Reply) {
+	t, ok := ctx.Value(traceKey{}).(*trace)
+	if !ok {
+		return
+	}
+	calls := t.finish()
+	if !t.sampled || (reply.Response == nil && len(calls) == 0) {
+		return
+	}
+
+	meta := requestmeta.FromContext(ctx)
+	entry := Entry{
+		Time:      time.Now(),
+		RequestID: meta.RequestID,
+		Route:     meta.Route,
+		Key:       meta.Key,
+		Source:    reply.Source,
+		Stream:    reply.Stream,
+		LatencyMS: reply.Latency.Milliseconds(),
+		Request:   marshal(redactRequest(reply.Request, l.opts.Redact)),
+		Response:  marshal(redactResponse(reply.Response, l.opts.Redact)),
+	}
+	if reply.Response != nil {
+		entry.Model = reply.Response.Model
+	}
+	if entry.Model == "" && reply.Request != nil {
+		entry.Model = reply.Request.Model
+	}
+	var names []string
+	for _, rec := range calls {
+		if !slices.Contains(names, rec.Provider) {
+			names = append(names, rec.Provider)
+		}
+		entry.Usage.PromptTokens += rec.Usage.PromptTokens
+		entry.Usage.CompletionTokens += rec.Usage.CompletionTokens
+		entry.Usage.TotalTokens += rec.Usage.TotalTokens
+		entry.Usage.CacheReadTokens += rec.Usage.CacheReadTokens
+		entry.Usage.CacheWriteTokens += rec.Usage.CacheWriteTokens
+		entry.EstimatedUsage = entry.EstimatedUsage || rec.Estimated
+		entry.Cost += rec.Cost
+	}
+	entry.Provider = strings.Join(names, ",")
+	l.send(ctx, entry)
+}
+
+// Record implements accounting.Recorder. Calls made for a request begun
+// with Begin go into its entry; shadow calls, calls made outside a client
+// request and calls that outlive the reply get entries of their own.
+func (l *Logger) Record(ctx context.Context, rec accounting.Record) {
+	meta := requestmeta.FromContext(ctx)
+	if t, ok := ctx.Value(traceKey{}).(*trace); ok && meta.Route != requestmeta.ShadowRoute && t.add(rec) {
+		return
+	}
+	if !l.sample() {
+		return
+	}
+
+	l.send(ctx, Entry{
+		Time:           rec.Time,
+		RequestID:      rec.RequestID,
+		Route:          meta.Route,
+		Key:            meta.Key,
+		Source:         SourceUpstream,
+		Provider:       rec.Provider,
+		Model:          rec.Model,
+		Stream:         rec.Stream,
+		LatencyMS:      rec.Latency.Milliseconds(),
+		Usage:          rec.Usage,
+		EstimatedUsage: rec.Estimated,
+		Cost:           rec.Cost,
+		Request:        marshal(redactRequest(rec.Request, l.opts.Redact)),
+		Response:       marshal(redactResponse(rec.Response, l.opts.Redact)),
+	})
+}
+
+func (l *Logger) sample() bool {
+	return l.opts.SampleRate >= 1 || rand.Float64() < l.opts.SampleRate
+}
+
+func (l *Logger) send(ctx context.Context, entry Entry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.entries <- entry:
+	default:
+		if l.dropped.Add(1)%100 == 1 {
+			l.logger.WarnContext(ctx, "audit buffer full, dropping entries", slog.Int64("dropped", l.dropped.Load()))
+		}
+	}
+}
+
+// marshal returns nil for a nil payload so it is left out of the entry.
+func marshal[T any](v *T) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// Close flushes buffered entries and closes the sink.
+func (l *Logger) Close() error {
+	l.closeOnce.Do(func() {
+		l.mu.Lock()
+		l.closed = true
+		close(l.entries)
+		l.mu.Unlock()
+		<-l.done
+	})
+	return l.sink.Close()
+}
+
+func (l *Logger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := l.sink.Write(batch); err != nil {
+			l.logger.Error("failed to write audit entries", slog.Int("count", len(batch)), slog.Any("error", err))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry, ok := <-l.entries:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+func redactRequest(req *models.ChatCompletionRequest, mode string) *models.ChatCompletionRequest {
+	if req == nil || mode == RedactNone {
+		return req
+	}
+	redacted := *req
+	redacted.Messages = append(redacted.Messages[:0:0], req.Messages...)
+	for i := range redacted.Messages {
+		redacted.Messages[i].Content = redactText(redacted.Messages[i].Content, mode)
+	}
+	return &redacted
+}
+
+func redactResponse(resp *models.ChatCompletionResponse, mode string) *models.ChatCompletionResponse {
+	if resp == nil || mode == RedactNone {
+		return resp
+	}
+	redacted := *resp
+	redacted.Choices = append(redacted.Choices[:0:0], resp.Choices...)
+	for i := range redacted.Choices {
+		redacted.Choices[i].Message.Content = redactText(redacted.Choices[i].Message.Content, mode)
+	}
+	return &redacted
+}
+
+func redactText(text, mode string) string {
+	if mode == RedactContent {
+		return "[REDACTED]"
+	}
+	return logging.Scrub(text)
+}
+
+// OpenSink opens the sink named by kind ("jsonl" or "sqlite") at path.
+func OpenSink(kind, path string, maxBytes int64, maxBackups int) (Sink, error) {
+	switch kind {
+	case "jsonl":
+		return NewJSONLSink(path, maxBytes, maxBackups)
+	case "sqlite":
+		return NewSQLiteSink(path)
+	default:
+		return nil, fmt.Errorf("unknown audit sink: %s", kind)
+	}
+}
@@ -0,0 +1,98 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// JSONLSink appends one JSON object per line and rotates the file once it
+// grows past maxBytes, keeping maxBackups old files as path.1, path.2, ...
+type JSONLSink struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+func NewJSONLSink(path string, maxBytes int64, maxBackups int) (*JSONLSink, error) {
+	s := &JSONLSink{
+		path:       path,
+		maxBytes:   maxBytes,
+		maxBackups: maxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *JSONLSink) Write(entries []Entry) error {
+	w := bufio.NewWriter(s.file)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+		line = append(line, '\n')
+
+		if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			if err := s.rotate(); err != nil {
+				return err
+			}
+			w = bufio.NewWriter(s.file)
+		}
+
+		n, err := w.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func (s *JSONLSink) Close() error {
+	return s.file.Close()
+}
+
+func (s *JSONLSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+func (s *JSONLSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.maxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxBackups))
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1))
+		}
+		if err := os.Rename(s.path, s.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	} else if err := os.Truncate(s.path, 0); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+
+	return s.open()
+}
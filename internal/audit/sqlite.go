@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+)
+
+const createAuditTable = `
+CREATE TABLE IF NOT EXISTS audit_log (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	time              TEXT NOT NULL,
+	request_id        TEXT NOT NULL,
+	route             TEXT NOT NULL,
+	key               TEXT NOT NULL,
+	source            TEXT NOT NULL,
+	provider          TEXT NOT NULL,
+	model             TEXT NOT NULL,
+	stream            INTEGER NOT NULL,
+	latency_ms        INTEGER NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	estimated_usage   INTEGER NOT NULL,
+	cost_usd          REAL NOT NULL,
+	request           TEXT,
+	response          TEXT
+);
+CREATE INDEX IF NOT EXISTS audit_log_time ON audit_log (time);
+CREATE INDEX IF NOT EXISTS audit_log_request_id ON audit_log (request_id);
+`
+
+const insertAuditEntry = `
+INSERT INTO audit_log (
+	time, request_id, route, key, source, provider, model, stream, latency_ms,
+	prompt_tokens, completion_tokens, estimated_usage, cost_usd, request, response
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLiteSink stores entries in a local SQLite database, one row per entry with
+// the request and response kept as JSON text.
+type SQLiteSink struct {
+	db *sql.DB
+}
+
+func NewSQLiteSink(path string) (*SQLiteSink, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit database: %w", err)
+	}
+	if _, err := db.Exec(createAuditTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create audit table: %w", err)
+	}
+	return &SQLiteSink{db: db}, nil
+}
+
+func (s *SQLiteSink) Write(entries []Entry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(insertAuditEntry)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		if _, err := stmt.Exec(
+			e.Time.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			e.RequestID, e.Route, e.Key, e.Source, e.Provider, e.Model, e.Stream, e.LatencyMS,
+			e.Usage.PromptTokens, e.Usage.CompletionTokens, e.EstimatedUsage, e.Cost,
+			payload(e.Request), payload(e.Response),
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// payload stores a missing payload as NULL rather than the text "null".
+func payload(data json.RawMessage) any {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+func (s *SQLiteSink) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,405 @@
+package batches
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// EndpointChatCompletions is the only endpoint batches can target.
+const EndpointChatCompletions = "/v1/chat/completions"
+
+// maxLineBytes bounds a single request line in an input file.
+const maxLineBytes = 4 << 20
+
+// CompleteFunc runs one chat completion from a batch.
+type CompleteFunc func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error)
+
+// StatusCodeFunc maps a completion error to the HTTP status a direct call
+// would have received.
+type StatusCodeFunc func(err error) int
+
+type inputLine struct {
+	CustomID string                        `json:"custom_id"`
+	Method   string                        `json:"method"`
+	URL      string                        `json:"url"`
+	Body     *models.ChatCompletionRequest `json:"body"`
+}
+
+type outputLine struct {
+	ID       string          `json:"id"`
+	CustomID string          `json:"custom_id"`
+	Response *outputResponse `json:"response"`
+	Error    *BatchError     `json:"error"`
+}
+
+type outputResponse struct {
+	StatusCode int    `json:"status_code"`
+	RequestID  string `json:"request_id"`
+	Body       any    `json:"body"`
+}
+
+// Runner executes batches, sharing one concurrency limit across all of them
+// and every provider. Batches that were unfinished when the router stopped
+// are resumed on start, skipping the requests that already have results.
+type Runner struct {
+	store      *Store
+	complete   CompleteFunc
+	statusCode StatusCodeFunc
+	logger     *slog.Logger
+	slots      chan struct{}
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu         sync.Mutex
+	cancelling map[string]chan struct{}
+}
+
+func NewRunner(store *Store, complete CompleteFunc, statusCode StatusCodeFunc, concurrency int, logger *slog.Logger) (*Runner, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Runner{
+		store:      store,
+		complete:   complete,
+		statusCode: statusCode,
+		logger:     logger,
+		slots:      make(chan struct{}, max(concurrency, 1)),
+		ctx:        ctx,
+		cancel:     cancel,
+		cancelling: make(map[string]chan struct{}),
+	}
+
+	unfinished, err := store.unfinishedBatches(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to load unfinished batches: %w", err)
+	}
+	for _, b := range unfinished {
+		logger.Info("resuming batch", slog.String("batch_id", b.ID), slog.String("status", string(b.Status)))
+		r.start(b)
+	}
+	return r, nil
+}
+
+func (r *Runner) Get(ctx context.Context, key, id string) (*Batch, error) {
+	return r.store.GetBatch(ctx, key, id)
+}
+
+func (r *Runner) List(ctx context.Context, key string, limit int) ([]*Batch, error) {
+	return r.store.ListBatches(ctx, key, limit)
+}
+
+// Create queues a batch over an uploaded input file. The file's contents
+// are validated once the batch starts, as with OpenAI.
+func (r *Runner) Create(ctx context.Context, key, inputFileID, endpoint, completionWindow string, metadata map[string]string) (*Batch, error) {
+	file, err := r.store.GetFile(ctx, key, inputFileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.Purpose != PurposeBatch {
+		return nil, fmt.Errorf("%w: purpose must be %q", ErrInvalidInput, PurposeBatch)
+	}
+
+	b := &Batch{
+		ID:               newID("batch_"),
+		Object:           "batch",
+		Endpoint:         endpoint,
+		InputFileID:      inputFileID,
+		CompletionWindow: completionWindow,
+		Status:           StatusValidating,
+		CreatedAt:        time.Now().Unix(),
+		Metadata:         metadata,
+		Key:              key,
+	}
+	if err := r.store.InsertBatch(ctx, b); err != nil {
+		return nil, err
+	}
+	r.start(b)
+	return b, nil
+}
+
+// Cancel stops dispatching a batch's remaining requests. Requests already
+// running finish, and the results so far are written out.
+func (r *Runner) Cancel(ctx context.Context, key, id string) (*Batch, error) {
+	b, err := r.store.GetBatch(ctx, key, id)
+	if err != nil {
+		return nil, err
+	}
+	if b.Status != StatusValidating && b.Status != StatusInProgress {
+		return b, nil
+	}
+
+	b.Status = StatusCancelling
+	b.CancellingAt = now()
+	if ok, err := r.store.UpdateBatch(ctx, b, StatusValidating, StatusInProgress); err != nil || !ok {
+		return r.store.GetBatch(ctx, key, id)
+	}
+
+	r.mu.Lock()
+	if ch, ok := r.cancelling[id]; ok {
+		close(ch)
+		delete(r.cancelling, id)
+	}
+	r.mu.Unlock()
+	return r.store.GetBatch(ctx, key, id)
+}
+
+// Close stops the runner. Unfinished batches resume on the next start.
+func (r *Runner) Close() error {
+	r.cancel()
+	r.wg.Wait()
+	return r.store.Close()
+}
+
+func (r *Runner) start(b *Batch) {
+	cancelled := make(chan struct{})
+	r.mu.Lock()
+	r.cancelling[b.ID] = cancelled
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer func() {
+			r.mu.Lock()
+			delete(r.cancelling, b.ID)
+			r.mu.Unlock()
+		}()
+		if err := r.process(b, cancelled); err != nil && r.ctx.Err() == nil {
+			r.logger.Error("batch failed", slog.String("batch_id", b.ID), slog.Any("error", err))
+		}
+	}()
+}
+
+func (r *Runner) process(b *Batch, cancelled <-chan struct{}) error {
+	ctx := r.ctx
+
+	if b.Status == StatusValidating || b.Status == StatusInProgress {
+		lines, errs, err := r.readInput(ctx, b)
+		if err != nil {
+			return err
+		}
+		if len(errs) > 0 {
+			b.Status = StatusFailed
+			b.FailedAt = now()
+			b.Errors = &BatchErrors{Object: "list", Data: errs}
+			_, err := r.store.UpdateBatch(ctx, b, StatusValidating)
+			return err
+		}
+
+		started := true
+		if b.Status == StatusValidating {
+			b.Status = StatusInProgress
+			b.InProgressAt = now()
+			b.RequestCounts.Total = len(lines)
+			// Not updated means the batch was cancelled while validating.
+			if started, err = r.store.UpdateBatch(ctx, b, StatusValidating); err != nil {
+				return err
+			}
+		}
+
+		if started {
+			if err := r.run(ctx, b, lines, cancelled); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+	}
+
+	// Re-read the status: the batch may have been cancelled meanwhile.
+	current, err := r.store.GetBatch(ctx, b.Key, b.ID)
+	if err != nil {
+		return err
+	}
+	*b = *current
+	return r.finalize(ctx, b)
+}
+
+func (r *Runner) readInput(ctx context.Context, b *Batch) ([]inputLine, []BatchError, error) {
+	_, f, err := r.store.OpenFile(ctx, b.Key, b.InputFileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var lines []inputLine
+	var errs []BatchError
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), maxLineBytes)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		line := n
+		var in inputLine
+		switch err := json.Unmarshal(scanner.Bytes(), &in); {
+		case err != nil:
+			errs = append(errs, BatchError{Code: "invalid_json_line", Message: err.Error(), Line: &line})
+		case in.CustomID == "":
+			errs = append(errs, BatchError{Code: "missing_custom_id", Message: "custom_id is required", Line: &line})
+		case seen[in.CustomID]:
+			errs = append(errs, BatchError{Code: "duplicate_custom_id", Message: "custom_id must be unique: " + in.CustomID, Line: &line})
+		case in.Method != http.MethodPost:
+			errs = append(errs, BatchError{Code: "invalid_method", Message: "method must be POST", Line: &line})
+		case in.URL != b.Endpoint:
+			errs = append(errs, BatchError{Code: "mismatched_url", Message: "url must match the batch endpoint " + b.Endpoint, Line: &line})
+		case in.Body == nil || in.Body.Model == "" || len(in.Body.Messages) == 0 && in.Body.Template == nil:
+			errs = append(errs, BatchError{Code: "invalid_request", Message: "body needs a model and messages", Line: &line})
+		default:
+			seen[in.CustomID] = true
+			in.Body.Stream = false
+			in.Body.StreamOptions = nil
+			lines = append(lines, in)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, BatchError{Code: "invalid_file", Message: err.Error()})
+	}
+	if len(lines) == 0 && len(errs) == 0 {
+		errs = append(errs, BatchError{Code: "empty_file", Message: "the input file has no requests"})
+	}
+	return lines, errs, nil
+}
+
+// run dispatches every request without a saved result, stopping early if
+// the batch is cancelled or the runner closes.
+func (r *Runner) run(ctx context.Context, b *Batch, lines []inputLine, cancelled <-chan struct{}) error {
+	finished, err := r.store.finishedLines(ctx, b.ID)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for i, in := range lines {
+		if finished[i] {
+			continue
+		}
+		select {
+		case r.slots <- struct{}{}:
+		case <-cancelled:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-r.slots }()
+			r.execute(ctx, b, i, in)
+		}()
+	}
+	return nil
+}
+
+func (r *Runner) execute(ctx context.Context, b *Batch, i int, in inputLine) {
+	out := outputLine{ID: newID("batch_req_"), CustomID: in.CustomID}
+	reqCtx := requestmeta.WithMeta(ctx, requestmeta.Meta{
+		RequestID: out.ID,
+		Route:     "/v1/batches",
+		Key:       b.Key,
+	})
+
+	resp, err := r.complete(reqCtx, in.Body)
+	if err != nil && ctx.Err() != nil {
+		// Interrupted by shutdown; the request runs again on resume.
+		return
+	}
+	if err != nil {
+		status := r.statusCode(err)
+		out.Response = &outputResponse{
+			StatusCode: status,
+			RequestID:  out.ID,
+			Body:       map[string]any{"error": map[string]string{"message": err.Error()}},
+		}
+		code := "server_error"
+		if status < http.StatusInternalServerError {
+			code = "invalid_request"
+		}
+		out.Error = &BatchError{Code: code, Message: err.Error()}
+	} else {
+		out.Response = &outputResponse{StatusCode: http.StatusOK, RequestID: out.ID, Body: resp}
+	}
+
+	data, err := json.Marshal(out)
+	if err == nil {
+		err = r.store.saveResult(context.WithoutCancel(ctx), b.ID, i, out.Error == nil, data)
+	}
+	if err != nil {
+		r.logger.ErrorContext(reqCtx, "failed to save batch result", slog.String("batch_id", b.ID), slog.Any("error", err))
+	}
+}
+
+// finalize writes the output and error files and moves the batch to its
+// final status.
+func (r *Runner) finalize(ctx context.Context, b *Batch) error {
+	from := b.Status
+	final := StatusCompleted
+	switch from {
+	case StatusCancelling:
+		final = StatusCancelled
+	case StatusInProgress:
+		b.Status = StatusFinalizing
+		b.FinalizingAt = now()
+		if _, err := r.store.UpdateBatch(ctx, b, StatusInProgress); err != nil {
+			return err
+		}
+		from = StatusFinalizing
+	case StatusFinalizing:
+	default:
+		return nil
+	}
+
+	output, errorsFile, counts, err := r.store.writeResults(ctx, b)
+	if err != nil {
+		return err
+	}
+	if output != nil {
+		b.OutputFileID = &output.ID
+	}
+	if errorsFile != nil {
+		b.ErrorFileID = &errorsFile.ID
+	}
+	b.RequestCounts.Completed = counts.Completed
+	b.RequestCounts.Failed = counts.Failed
+	b.Status = final
+	if final == StatusCancelled {
+		b.CancelledAt = now()
+	} else {
+		b.CompletedAt = now()
+	}
+
+	ok, err := r.store.UpdateBatch(ctx, b, from)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("batch changed while finalizing")
+	}
+	r.logger.Info("batch finished",
+		slog.String("batch_id", b.ID),
+		slog.String("status", string(b.Status)),
+		slog.Int("completed", counts.Completed),
+		slog.Int("failed", counts.Failed),
+	)
+	return nil
+}
+
+func now() *int64 {
+	t := time.Now().Unix()
+	return &t
+}
@@ -0,0 +1,330 @@
+package batches
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const createBatchTables = `
+CREATE TABLE IF NOT EXISTS files (
+	id         TEXT PRIMARY KEY,
+	key        TEXT NOT NULL,
+	file       TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS batches (
+	id         TEXT PRIMARY KEY,
+	key        TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	created_at INTEGER NOT NULL,
+	batch      TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS batches_key_created_at ON batches (key, created_at);
+CREATE TABLE IF NOT EXISTS batch_results (
+	batch_id   TEXT NOT NULL,
+	line       INTEGER NOT NULL,
+	ok         INTEGER NOT NULL,
+	result     TEXT NOT NULL,
+	PRIMARY KEY (batch_id, line)
+);
+`
+
+// Store keeps file contents under dir and everything else in a SQLite
+// database there. Each request's result is saved as soon as it finishes,
+// which is what lets a batch resume after a restart.
+type Store struct {
+	db  *sql.DB
+	dir string
+}
+
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create batch directory: %w", err)
+	}
+	db, err := sql.Open("sqlite", filepath.Join(dir, "batches.db")+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open batch database: %w", err)
+	}
+	if _, err := db.Exec(createBatchTables); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create batch tables: %w", err)
+	}
+	return &Store{db: db, dir: dir}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func (s *Store) filePath(id string) string {
+	return filepath.Join(s.dir, "files", id)
+}
+
+// CreateFile stores content read from r, up to maxBytes.
+func (s *Store) CreateFile(ctx context.Context, key, filename, purpose string, r io.Reader, maxBytes int64) (*File, error) {
+	file := &File{
+		ID:        newID("file-"),
+		Object:    "file",
+		CreatedAt: time.Now().Unix(),
+		Filename:  filename,
+		Purpose:   purpose,
+		Key:       key,
+	}
+
+	f, err := os.Create(s.filePath(file.ID))
+	if err != nil {
+		return nil, err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, maxBytes+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxBytes {
+		err = fmt.Errorf("%w: limit is %d bytes", ErrFileTooLarge, maxBytes)
+	}
+	if err != nil {
+		os.Remove(s.filePath(file.ID))
+		return nil, err
+	}
+	file.Bytes = n
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO files (id, key, file) VALUES (?, ?, ?)`, file.ID, key, string(data)); err != nil {
+		os.Remove(s.filePath(file.ID))
+		return nil, err
+	}
+	return file, nil
+}
+
+func (s *Store) GetFile(ctx context.Context, key, id string) (*File, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT file FROM files WHERE id = ? AND key = ?`, id, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := &File{Key: key}
+	return file, json.Unmarshal([]byte(data), file)
+}
+
+// OpenFile returns the content of a file owned by key.
+func (s *Store) OpenFile(ctx context.Context, key, id string) (*File, io.ReadCloser, error) {
+	file, err := s.GetFile(ctx, key, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(s.filePath(id))
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, f, nil
+}
+
+func (s *Store) InsertBatch(ctx context.Context, b *Batch) error {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO batches (id, key, status, created_at, batch) VALUES (?, ?, ?, ?, ?)`,
+		b.ID, b.Key, b.Status, b.CreatedAt, string(data),
+	)
+	return err
+}
+
+// UpdateBatch saves b only while its stored status is one of from, and
+// reports whether it did.
+func (s *Store) UpdateBatch(ctx context.Context, b *Batch, from ...Status) (bool, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+	for _, status := range from {
+		res, err := s.db.ExecContext(ctx,
+			`UPDATE batches SET status = ?, batch = ? WHERE id = ? AND status = ?`,
+			b.Status, string(data), b.ID, status,
+		)
+		if err != nil {
+			return false, err
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetBatch returns a batch owned by key with its request counts filled in
+// from the results saved so far.
+func (s *Store) GetBatch(ctx context.Context, key, id string) (*Batch, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT batch FROM batches WHERE id = ? AND key = ?`, id, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.decodeBatch(ctx, key, data)
+}
+
+func (s *Store) ListBatches(ctx context.Context, key string, limit int) ([]*Batch, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT batch FROM batches WHERE key = ? ORDER BY created_at DESC LIMIT ?`, key, limit)
+	if err != nil {
+		return nil, err
+	}
+	var raw []string
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		raw = append(raw, data)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	batches := make([]*Batch, 0, len(raw))
+	for _, data := range raw {
+		b, err := s.decodeBatch(ctx, key, data)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, nil
+}
+
+// unfinishedBatches returns every batch that still needs work, for resuming
+// after a restart.
+func (s *Store) unfinishedBatches(ctx context.Context) ([]*Batch, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, batch FROM batches WHERE status IN (?, ?, ?, ?) ORDER BY created_at`,
+		StatusValidating, StatusInProgress, StatusFinalizing, StatusCancelling)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var batches []*Batch
+	for rows.Next() {
+		var key, data string
+		if err := rows.Scan(&key, &data); err != nil {
+			return nil, err
+		}
+		b := &Batch{}
+		if err := json.Unmarshal([]byte(data), b); err != nil {
+			return nil, err
+		}
+		b.Key = key
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+func (s *Store) decodeBatch(ctx context.Context, key, data string) (*Batch, error) {
+	b := &Batch{Key: key}
+	if err := json.Unmarshal([]byte(data), b); err != nil {
+		return nil, err
+	}
+	if b.Status == StatusInProgress || b.Status == StatusCancelling {
+		var completed, failed int
+		err := s.db.QueryRowContext(ctx,
+			`SELECT COALESCE(SUM(ok), 0), COALESCE(SUM(1 - ok), 0) FROM batch_results WHERE batch_id = ?`, b.ID,
+		).Scan(&completed, &failed)
+		if err != nil {
+			return nil, err
+		}
+		b.RequestCounts.Completed = completed
+		b.RequestCounts.Failed = failed
+	}
+	return b, nil
+}
+
+func (s *Store) saveResult(ctx context.Context, batchID string, line int, ok bool, result []byte) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR IGNORE INTO batch_results (batch_id, line, ok, result) VALUES (?, ?, ?, ?)`,
+		batchID, line, ok, string(result))
+	return err
+}
+
+// finishedLines returns the input lines that already have a result.
+func (s *Store) finishedLines(ctx context.Context, batchID string) (map[int]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT line FROM batch_results WHERE batch_id = ?`, batchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make(map[int]bool)
+	for rows.Next() {
+		var line int
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines[line] = true
+	}
+	return lines, rows.Err()
+}
+
+// writeResults writes saved results to two new files, one for successes
+// and one for failures, in input order. A file is only created if it has
+// lines.
+func (s *Store) writeResults(ctx context.Context, b *Batch) (output, errorsFile *File, counts RequestCounts, err error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT ok, result FROM batch_results WHERE batch_id = ? ORDER BY line`, b.ID)
+	if err != nil {
+		return nil, nil, counts, err
+	}
+	var okLines, errLines []byte
+	for rows.Next() {
+		var ok bool
+		var result string
+		if err := rows.Scan(&ok, &result); err != nil {
+			rows.Close()
+			return nil, nil, counts, err
+		}
+		if ok {
+			okLines = append(okLines, result...)
+			okLines = append(okLines, '\n')
+			counts.Completed++
+		} else {
+			errLines = append(errLines, result...)
+			errLines = append(errLines, '\n')
+			counts.Failed++
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, counts, err
+	}
+
+	if len(okLines) > 0 {
+		if output, err = s.CreateFile(ctx, b.Key, b.ID+"_output.jsonl", PurposeBatchOutput, bytes.NewReader(okLines), int64(len(okLines))); err != nil {
+			return nil, nil, counts, err
+		}
+	}
+	if len(errLines) > 0 {
+		if errorsFile, err = s.CreateFile(ctx, b.Key, b.ID+"_error.jsonl", PurposeBatchOutput, bytes.NewReader(errLines), int64(len(errLines))); err != nil {
+			return nil, nil, counts, err
+		}
+	}
+	return output, errorsFile, counts, nil
+}
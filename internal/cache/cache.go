@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+// Store holds complete responses by key until they expire.
+type Store interface {
+	Get(ctx context.Context, key string) (*models.ChatCompletionResponse, bool)
+	Set(ctx context.Context, key string, resp *models.ChatCompletionResponse, ttl time.Duration) error
+}
+
+// OpenStore opens the store named by backend: "memory" (an LRU holding up
+// to capacity entries) or "disk" (up to capacity files under dir).
+func OpenStore(backend, dir string, capacity int) (Store, error) {
+	switch backend {
+	case "memory":
+		return NewLRU(capacity), nil
+	case "disk":
+		return NewDiskStore(dir, capacity)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %s", backend)
+	}
+}
+
+type normalizedMessage struct {
+	Role    string `json:"role"`
+	Name    string `json:"name,omitempty"`
+	Content string `json:"content"`
+}
+
+type normalizedRequest struct {
+	Scope       string              `json:"scope"`
+	Model       string              `json:"model"`
+	Messages    []normalizedMessage `json:"messages"`
+	MaxTokens   *int64              `json:"max_tokens,omitempty"`
+	Temperature *float64            `json:"temperature,omitempty"`
+	TopP        *float64            `json:"top_p,omitempty"`
+	Stop        []string            `json:"stop,omitempty"`
+}
+
+// Key derives the cache key for req within scope (typically the client key
+// fingerprint). Fields that do not change the completion, such as stream and
+// stream_options, are left out so streamed and non-streamed calls share
+// entries.
+func Key(scope string, req *models.ChatCompletionRequest) string {
+	n := normalizedRequest{
+		Scope:       scope,
+		Model:       strings.ToLower(strings.TrimSpace(req.Model)),
+		Messages:    make([]normalizedMessage, len(req.Messages)),
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+	}
+	for i, msg := range req.Messages {
+		n.Messages[i] = normalizedMessage{
+			Role:    strings.ToLower(msg.Role),
+			Name:    msg.Name,
+			Content: msg.Content,
+		}
+	}
+	if len(req.Stop) > 0 {
+		n.Stop = append([]string(nil), req.Stop...)
+		sort.Strings(n.Stop)
+	}
+
+	data, _ := json.Marshal(n)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/llm-router/internal/models"
+)
+
+func TestKey(t *testing.T) {
+	temp := 0.0
+	base := func() *models.ChatCompletionRequest {
+		return &models.ChatCompletionRequest{
+			Model:       "gpt-4o",
+			Messages:    []models.Message{{Role: "user", Content: "hi"}},
+			Temperature: &temp,
+			Stop:        []string{"a", "b"},
+		}
+	}
+	tests := []struct {
+		name   string
+		scope  string
+		change func(*models.ChatCompletionRequest)
+		same   bool
+	}{
+		{"identical", "key-a", func(*models.ChatCompletionRequest) {}, true},
+		{"model case and spaces", "key-a", func(r *models.ChatCompletionRequest) { r.Model = " GPT-4o " }, true},
+		{"role case", "key-a", func(r *models.ChatCompletionRequest) { r.Messages[0].Role = "User" }, true},
+		{"stop order", "key-a", func(r *models.ChatCompletionRequest) { r.Stop = []string{"b", "a"} }, true},
+		{"stream", "key-a", func(r *models.ChatCompletionRequest) {
+			r.Stream = true
+			r.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+		}, true},
+		{"other scope", "key-b", func(*models.ChatCompletionRequest) {}, false},
+		{"other model", "key-a", func(r *models.ChatCompletionRequest) { r.Model = "gpt-4o-mini" }, false},
+		{"other content", "key-a", func(r *models.ChatCompletionRequest) { r.Messages[0].Content = "hello" }, false},
+		{"content case", "key-a", func(r *models.ChatCompletionRequest) { r.Messages[0].Content = "Hi" }, false},
+		{"other temperature", "key-a", func(r *models.ChatCompletionRequest) { one := 1.0; r.Temperature = &one }, false},
+		{"no temperature", "key-a", func(r *models.ChatCompletionRequest) { r.Temperature = nil }, false},
+		{"other stop", "key-a", func(r *models.ChatCompletionRequest) { r.Stop = []string{"a"} }, false},
+	}
+	want := Key("key-a", base())
+	for _, tt := range tests {
+		req := base()
+		tt.change(req)
+		if got := Key(tt.scope, req); (got == want) != tt.same {
+			t.Errorf("%s: same key = %v, want %v", tt.name, got == want, tt.same)
+		}
+	}
+}
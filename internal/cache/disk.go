@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+// sweepInterval is how often Set removes expired entries.
+const sweepInterval = time.Minute
+
+type diskEntry struct {
+	ExpiresAt time.Time                      `json:"expires_at"`
+	Response  *models.ChatCompletionResponse `json:"response"`
+}
+
+// DiskStore keeps one JSON file per entry under dir so the cache survives a
+// restart. It holds at most capacity entries, evicting the one closest to
+// expiry to make room, and removes expired files at most once a minute as
+// entries are written.
+type DiskStore struct {
+	dir      string
+	capacity int
+
+	mu        sync.Mutex
+	expiries  map[string]time.Time
+	lastSweep time.Time
+}
+
+// NewDiskStore opens the store under dir, picking up the entries a previous
+// run left there. A capacity of zero or less means no limit.
+func NewDiskStore(dir string, capacity int) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	s := &DiskStore{
+		dir:       dir,
+		capacity:  capacity,
+		expiries:  make(map[string]time.Time),
+		lastSweep: time.Now(),
+	}
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+	return s, nil
+}
+
+// load indexes the entries on disk, removing expired or unreadable ones and
+// temporary files left by an interrupted write.
+func (s *DiskStore) load() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, f := range files {
+		name := f.Name()
+		path := filepath.Join(s.dir, name)
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(path)
+			continue
+		}
+		key, ok := strings.CutSuffix(name, ".json")
+		if !ok {
+			continue
+		}
+		entry, err := readEntry(path)
+		if err != nil || now.After(entry.ExpiresAt) {
+			os.Remove(path)
+			continue
+		}
+		s.expiries[key] = entry.ExpiresAt
+	}
+	s.evict()
+	return nil
+}
+
+func (s *DiskStore) Get(ctx context.Context, key string) (*models.ChatCompletionResponse, bool) {
+	path := s.path(key)
+	entry, err := readEntry(path)
+	if err != nil {
+		return nil, false
+	}
+	if time.Now().After(entry.ExpiresAt) {
+		s.remove(key, entry.ExpiresAt)
+		return nil, false
+	}
+	return entry.Response, true
+}
+
+func (s *DiskStore) Set(ctx context.Context, key string, resp *models.ChatCompletionResponse, ttl time.Duration) error {
+	expiresAt := time.Now().Add(ttl)
+	data, err := json.Marshal(diskEntry{ExpiresAt: expiresAt, Response: resp})
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so readers never see a partial entry.
+	tmp, err := os.CreateTemp(s.dir, key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	s.expiries[key] = expiresAt
+	if now := time.Now(); now.Sub(s.lastSweep) >= sweepInterval {
+		s.lastSweep = now
+		s.sweep(now)
+	}
+	s.evict()
+	return nil
+}
+
+// sweep removes expired entries. The caller holds s.mu.
+func (s *DiskStore) sweep(now time.Time) {
+	for key, expiresAt := range s.expiries {
+		if now.After(expiresAt) {
+			os.Remove(s.path(key))
+			delete(s.expiries, key)
+		}
+	}
+}
+
+// evict removes the entries closest to expiry until the store is within
+// capacity. The caller holds s.mu.
+func (s *DiskStore) evict() {
+	for s.capacity > 0 && len(s.expiries) > s.capacity {
+		var victim string
+		var soonest time.Time
+		for key, expiresAt := range s.expiries {
+			if victim == "" || expiresAt.Before(soonest) {
+				victim, soonest = key, expiresAt
+			}
+		}
+		os.Remove(s.path(victim))
+		delete(s.expiries, victim)
+	}
+}
+
+// remove deletes an expired entry unless it has been written again since.
+func (s *DiskStore) remove(key string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.expiries[key].Equal(expiresAt) {
+		return
+	}
+	os.Remove(s.path(key))
+	delete(s.expiries, key)
+}
+
+func (s *DiskStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+func readEntry(path string) (*diskEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
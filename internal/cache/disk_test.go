@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+func response(text string) *models.ChatCompletionResponse {
+	return &models.ChatCompletionResponse{
+		Model: "gpt-4o",
+		Choices: []models.ChatCompletionChoice{{
+			Message:      models.ChatMessage{Role: "assistant", Content: text},
+			FinishReason: "stop",
+		}},
+	}
+}
+
+func files(t *testing.T, dir string) int {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(entries)
+}
+
+func TestDiskStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Set(ctx, "fresh", response("fresh"), time.Hour)
+	s.Set(ctx, "stale", response("stale"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if got, ok := s.Get(ctx, "fresh"); !ok || got.Choices[0].Message.Content != "fresh" {
+		t.Errorf("fresh entry = %v, %v", got, ok)
+	}
+	if _, ok := s.Get(ctx, "stale"); ok {
+		t.Error("expired entry was served")
+	}
+	if n := files(t, dir); n != 1 {
+		t.Errorf("%d files left, want the fresh entry only", n)
+	}
+}
+
+func TestDiskStoreSweepsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Set(ctx, "stale", response("stale"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	s.lastSweep = time.Now().Add(-sweepInterval)
+	s.Set(ctx, "fresh", response("fresh"), time.Hour)
+
+	if _, err := os.Stat(filepath.Join(dir, "stale.json")); !os.IsNotExist(err) {
+		t.Errorf("expired entry was not swept: %v", err)
+	}
+}
+
+func TestDiskStoreCapacity(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewDiskStore(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Set(ctx, "a", response("a"), time.Hour)
+	s.Set(ctx, "b", response("b"), 3*time.Hour)
+	s.Set(ctx, "c", response("c"), 2*time.Hour)
+
+	if _, ok := s.Get(ctx, "a"); ok {
+		t.Error("entry closest to expiry was not evicted")
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, ok := s.Get(ctx, key); !ok {
+			t.Errorf("entry %s was evicted", key)
+		}
+	}
+	if n := files(t, dir); n != 2 {
+		t.Errorf("%d files on disk, want 2", n)
+	}
+
+	// A restart with a smaller capacity picks up the entries and trims them.
+	s, err = NewDiskStore(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get(ctx, "b"); !ok {
+		t.Error("entry furthest from expiry was not kept across a restart")
+	}
+	if n := files(t, dir); n != 1 {
+		t.Errorf("%d files on disk after restart, want 1", n)
+	}
+}
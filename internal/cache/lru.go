@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+type lruEntry struct {
+	key       string
+	resp      *models.ChatCompletionResponse
+	expiresAt time.Time
+}
+
+// LRU is an in-memory Store that evicts the least recently used entry once
+// capacity is reached.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, key string) (*models.ChatCompletionResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.resp, true
+}
+
+func (c *LRU) Set(ctx context.Context, key string, resp *models.ChatCompletionResponse, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.resp = resp
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return nil
+	}
+
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, resp: resp, expiresAt: expiresAt})
+	for c.capacity > 0 && c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
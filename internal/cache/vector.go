@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+type vectorEntry struct {
+	scope     string
+	vector    []float32
+	resp      *models.ChatCompletionResponse
+	expiresAt time.Time
+}
+
+// VectorIndex is an in-memory nearest-neighbour index over unit vectors,
+// partitioned by scope. Search is a linear scan, which is fast enough for
+// the few thousand entries a router keeps; the oldest entry is evicted once
+// capacity is reached.
+type VectorIndex struct {
+	mu       sync.RWMutex
+	capacity int
+	scopes   map[string][]*vectorEntry
+	order    []*vectorEntry
+}
+
+func NewVectorIndex(capacity int) *VectorIndex {
+	return &VectorIndex{
+		capacity: capacity,
+		scopes:   make(map[string][]*vectorEntry),
+	}
+}
+
+// Search returns the closest live entry in scope whose cosine similarity to
+// vector is at least threshold.
+func (x *VectorIndex) Search(scope string, vector []float32, threshold float32) (*models.ChatCompletionResponse, float32, bool) {
+	query := normalize(vector)
+	now := time.Now()
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	var best *vectorEntry
+	var bestScore float32
+	for _, entry := range x.scopes[scope] {
+		if now.After(entry.expiresAt) || len(entry.vector) != len(query) {
+			continue
+		}
+		score := dot(query, entry.vector)
+		if score >= threshold && (best == nil || score > bestScore) {
+			best = entry
+			bestScore = score
+		}
+	}
+	if best == nil {
+		return nil, 0, false
+	}
+	return best.resp, bestScore, true
+}
+
+func (x *VectorIndex) Add(scope string, vector []float32, resp *models.ChatCompletionResponse, ttl time.Duration) {
+	entry := &vectorEntry{
+		scope:     scope,
+		vector:    normalize(vector),
+		resp:      resp,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.scopes[scope] = append(x.scopes[scope], entry)
+	x.order = append(x.order, entry)
+	for x.capacity > 0 && len(x.order) > x.capacity {
+		x.remove(x.order[0])
+		x.order = x.order[1:]
+	}
+}
+
+func (x *VectorIndex) remove(entry *vectorEntry) {
+	entries := x.scopes[entry.scope]
+	for i, e := range entries {
+		if e == entry {
+			x.scopes[entry.scope] = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
+	if len(x.scopes[entry.scope]) == 0 {
+		delete(x.scopes, entry.scope)
+	}
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, f := range v {
+		sum += float64(f) * float64(f)
+	}
+	norm := float32(math.Sqrt(sum))
+	out := make([]float32, len(v))
+	if norm == 0 {
+		return out
+	}
+	for i, f := range v {
+		out[i] = f / norm
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,98 @@
+package classifier
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/tokenizer"
+)
+
+// Tier is the class of model a request needs.
+type Tier string
+
+const (
+	TierCheap  Tier = "cheap"
+	TierStrong Tier = "strong"
+)
+
+// Decision explains how a request was classified. Reasons name the signals
+// that added to Score, so decisions can be audited from the logs.
+type Decision struct {
+	Tier    Tier
+	Score   int
+	Reasons []string
+	// Source is "heuristic" or "model".
+	Source string
+}
+
+// Heuristic scores a request from its shape. Each signal adds points, and
+// a total of Threshold or more needs the strong tier.
+type Heuristic struct {
+	Threshold        int
+	LongPromptTokens int64
+}
+
+var (
+	codePattern = regexp.MustCompile("(?m)```|^\\s*(func|def|class|import|package|#include|public|private|const|let|var|SELECT|FROM)\\b|[;{}]\\s*$")
+
+	reasoningTerms = []string{
+		"prove", "derive", "step by step", "analyze", "analyse", "debug",
+		"refactor", "optimize", "architecture", "algorithm", "complexity",
+		"trade-off", "tradeoff", "compare", "design", "explain why",
+	}
+)
+
+func (h Heuristic) Classify(req *models.ChatCompletionRequest) Decision {
+	d := Decision{Source: "heuristic"}
+
+	var prompt strings.Builder
+	for _, m := range req.Messages {
+		if m.Role == "user" {
+			prompt.WriteString(m.Content)
+			prompt.WriteString("\n")
+		}
+	}
+	text := prompt.String()
+
+	tokens := tokenizer.ForModel(req.Model).Count(text)
+	switch {
+	case tokens >= h.LongPromptTokens:
+		d.add(2, "long_prompt")
+	case tokens >= h.LongPromptTokens/4:
+		d.add(1, "medium_prompt")
+	}
+
+	if codePattern.MatchString(text) {
+		d.add(2, "code")
+	}
+
+	lower := strings.ToLower(text)
+	terms := 0
+	for _, term := range reasoningTerms {
+		if strings.Contains(lower, term) {
+			terms++
+		}
+	}
+	if terms > 0 {
+		d.add(min(terms, 2), "reasoning")
+	}
+
+	if len(req.Messages) > 6 {
+		d.add(1, "long_conversation")
+	}
+	if req.MaxTokens != nil && *req.MaxTokens > 1000 {
+		d.add(1, "long_output")
+	}
+
+	d.Tier = TierCheap
+	if d.Score >= h.Threshold {
+		d.Tier = TierStrong
+	}
+	return d
+}
+
+func (d *Decision) add(points int, reason string) {
+	d.Score += points
+	d.Reasons = append(d.Reasons, reason)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -11,17 +12,245 @@ type Config struct {
 	Port           string
 	RequestTimeout time.Duration
 
-	LogLevel string
+	LogLevel  string
+	LogFormat string
+
+	// TracingEndpoint is the OTLP/HTTP collector URL; tracing is off when empty.
+	TracingEndpoint string
+	ServiceName     string
+
+	Audit AuditConfig
+	Cache CacheConfig
+
+	SemanticCache SemanticCacheConfig
+
+	// CoalesceRequests shares one upstream call between identical requests
+	// that are in flight at the same time.
+	CoalesceRequests bool
+
+	// PromptCachePolicy is "auto" to let the router place Anthropic prompt
+	// caching breakpoints, or "off" to pass through client hints only.
+	PromptCachePolicy    string
+	PromptCacheMinTokens int
+
+	Context ContextConfig
+
+	// SessionStore is "memory" or "sqlite" to enable the sessions API, which
+	// is off when empty. SessionPath is the SQLite database file.
+	SessionStore string
+	SessionPath  string
+
+	// AdminAPIKey protects the /admin routes when set.
+	AdminAPIKey string
+
+	Jobs    JobsConfig
+	Batches BatchesConfig
+	Shadow  ShadowConfig
+
+	File FileConfig
 }
 
-func LoadConfig() *Config {
+// AuditConfig controls the prompt/completion audit log. It is disabled when
+// Sink is empty.
+type AuditConfig struct {
+	Sink       string
+	Path       string
+	SampleRate float64
+	Redact     string
+	MaxSizeMB  int
+	MaxBackups int
+}
+
+func LoadConfig() (*Config, error) {
+	file, err := loadFileConfig(getEnv("CONFIG_FILE", ""))
+	if err != nil {
+		return nil, err
+	}
+	sessionStore := getEnv("SESSION_STORE", "")
+
 	return &Config{
 		APIKeys:        loadAPIKeys(),
 		Port:           getEnv("PORT", "8080"),
 		RequestTimeout: loadRequestTimeout(),
 		LogLevel:       getEnv("LOG_LEVEL", "info"),
+		LogFormat:      getEnv("LOG_FORMAT", "json"),
+
+		TracingEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		ServiceName:     getEnv("OTEL_SERVICE_NAME", "llm-router"),
+
+		Audit: loadAuditConfig(),
+		Cache: loadCacheConfig(),
+
+		SemanticCache: loadSemanticCacheConfig(),
+
+		CoalesceRequests: getEnvBool("COALESCE_REQUESTS", false),
+
+		PromptCachePolicy:    getEnv("PROMPT_CACHE_POLICY", "off"),
+		PromptCacheMinTokens: getEnvInt("PROMPT_CACHE_MIN_TOKENS", 1024),
+
+		Context: loadContextConfig(sessionStore != ""),
+
+		SessionStore: sessionStore,
+		SessionPath:  getEnv("SESSION_PATH", "sessions.db"),
+
+		AdminAPIKey: getEnv("ADMIN_API_KEY", ""),
+
+		Jobs:    loadJobsConfig(),
+		Batches: loadBatchesConfig(),
+		Shadow:  loadShadowConfig(),
+
+		File: file,
+	}, nil
+}
+
+// CacheConfig controls the exact-match response cache. Backend is "memory",
+// "disk", or empty to disable caching.
+type CacheConfig struct {
+	Backend  string
+	Mode     string
+	TTL      time.Duration
+	Capacity int
+	Dir      string
+}
+
+// SemanticCacheConfig controls the embedding-similarity cache, which uses
+// the OpenAI key for embeddings.
+type SemanticCacheConfig struct {
+	Enabled        bool
+	Mode           string
+	Threshold      float64
+	EmbeddingModel string
+	TTL            time.Duration
+	Capacity       int
+}
+
+func loadSemanticCacheConfig() SemanticCacheConfig {
+	return SemanticCacheConfig{
+		Enabled:        getEnvBool("SEMANTIC_CACHE_ENABLED", false),
+		Mode:           getEnv("SEMANTIC_CACHE_MODE", "deterministic"),
+		Threshold:      getEnvFloat("SEMANTIC_CACHE_THRESHOLD", 0.95),
+		EmbeddingModel: getEnv("SEMANTIC_CACHE_EMBEDDING_MODEL", "text-embedding-3-small"),
+		TTL:            time.Duration(getEnvInt("SEMANTIC_CACHE_TTL", 3600)) * time.Second,
+		Capacity:       getEnvInt("SEMANTIC_CACHE_CAPACITY", 5000),
+	}
+}
+
+func loadCacheConfig() CacheConfig {
+	return CacheConfig{
+		Backend:  getEnv("CACHE_BACKEND", ""),
+		Mode:     getEnv("CACHE_MODE", "deterministic"),
+		TTL:      time.Duration(getEnvInt("CACHE_TTL", 3600)) * time.Second,
+		Capacity: getEnvInt("CACHE_CAPACITY", 1000),
+		Dir:      getEnv("CACHE_DIR", "cache"),
+	}
+}
+
+func loadAuditConfig() AuditConfig {
+	sink := getEnv("AUDIT_SINK", "")
+	defaultPath := "audit.jsonl"
+	if sink == "sqlite" {
+		defaultPath = "audit.db"
+	}
+
+	return AuditConfig{
+		Sink:       sink,
+		Path:       getEnv("AUDIT_PATH", defaultPath),
+		SampleRate: getEnvFloat("AUDIT_SAMPLE_RATE", 1),
+		Redact:     getEnv("AUDIT_REDACT", "secrets"),
+		MaxSizeMB:  getEnvInt("AUDIT_MAX_SIZE_MB", 100),
+		MaxBackups: getEnvInt("AUDIT_MAX_BACKUPS", 5),
+	}
+}
+
+// ContextConfig controls trimming of requests that would overflow the
+// model's context window. Strategy is drop_oldest, keep_last, middle_out,
+// summarize, or "off" to send requests unchanged. It defaults to off, or to
+// drop_oldest when sessions are enabled, since stored conversations keep
+// growing.
+type ContextConfig struct {
+	Strategy      string
+	KeepLast      int
+	ReserveTokens int
+	SummaryModel  string
+}
+
+func loadContextConfig(sessions bool) ContextConfig {
+	strategy := "off"
+	if sessions {
+		strategy = "drop_oldest"
+	}
+	return ContextConfig{
+		Strategy:      getEnv("CONTEXT_STRATEGY", strategy),
+		KeepLast:      getEnvInt("CONTEXT_KEEP_LAST", 10),
+		ReserveTokens: getEnvInt("CONTEXT_RESERVE_TOKENS", 1024),
+		SummaryModel:  getEnv("CONTEXT_SUMMARY_MODEL", "gpt-4o-mini"),
+	}
+}
+
+// JobsConfig controls the asynchronous job API. Jobs are queued in the
+// SQLite database at Path and run by Workers workers at a time. Webhooks
+// only go to WebhookAllowedHosts when set, and to public addresses
+// otherwise; they are signed with WebhookSecret when set.
+type JobsConfig struct {
+	Enabled             bool
+	Path                string
+	Workers             int
+	Timeout             time.Duration
+	WebhookAllowedHosts []string
+	WebhookSecret       string
+}
+
+func loadJobsConfig() JobsConfig {
+	var allowed []string
+	for _, host := range strings.Split(getEnv("JOBS_WEBHOOK_ALLOWED_HOSTS", ""), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			allowed = append(allowed, host)
+		}
+	}
+	return JobsConfig{
+		Enabled:             getEnvBool("JOBS_ENABLED", false),
+		Path:                getEnv("JOBS_PATH", "jobs.db"),
+		Workers:             getEnvInt("JOBS_WORKERS", 4),
+		Timeout:             time.Duration(getEnvInt("JOBS_TIMEOUT", 600)) * time.Second,
+		WebhookAllowedHosts: allowed,
+		WebhookSecret:       getEnv("JOBS_WEBHOOK_SECRET", ""),
+	}
+}
+
+// BatchesConfig controls the batch API. Uploaded files and results live
+// under Dir; Concurrency bounds the requests in flight across all batches.
+type BatchesConfig struct {
+	Enabled     bool
+	Dir         string
+	Concurrency int
+	MaxFileMB   int
+}
+
+func loadBatchesConfig() BatchesConfig {
+	return BatchesConfig{
+		Enabled:     getEnvBool("BATCHES_ENABLED", false),
+		Dir:         getEnv("BATCH_DIR", "batches"),
+		Concurrency: getEnvInt("BATCH_CONCURRENCY", 8),
+		MaxFileMB:   getEnvInt("BATCH_MAX_FILE_MB", 200),
+	}
+}
+
+// ShadowConfig controls where shadow comparisons are stored and how many
+// shadow requests may run at once. Policies come from the config file.
+type ShadowConfig struct {
+	Path        string
+	MaxInFlight int
+	Timeout     time.Duration
+}
+
+func loadShadowConfig() ShadowConfig {
+	return ShadowConfig{
+		Path:        getEnv("SHADOW_PATH", "shadow.db"),
+		MaxInFlight: getEnvInt("SHADOW_MAX_IN_FLIGHT", 16),
+		Timeout:     time.Duration(getEnvInt("SHADOW_TIMEOUT", 120)) * time.Second,
 	}
 }
+
 func loadAPIKeys() map[string]string {
 	apiKeys := make(map[string]string)
 	apiKeys["openai"] = getEnv("OPENAI_API_KEY", "abc")
@@ -37,6 +266,30 @@ func getEnv(key string, defaultValue string) string {
 	return value
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(getEnv(key, ""), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func loadRequestTimeout() time.Duration {
 	timeoutStr := getEnv("REQUEST_TIMEOUT", "30")
 	timeout, err := strconv.Atoi(timeoutStr)
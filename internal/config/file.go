@@ -0,0 +1,272 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// FileConfig holds the structured settings that do not fit in environment
+// variables. It is read from the JSON file named by CONFIG_FILE.
+type FileConfig struct {
+	PII        []PIIPolicy       `json:"pii,omitempty"`
+	Guardrails []GuardrailPolicy `json:"guardrails,omitempty"`
+	// Plugins run around every provider call in the order listed.
+	Plugins []PluginConfig `json:"plugins,omitempty"`
+
+	Templates     []PromptTemplate     `json:"templates,omitempty"`
+	SystemPrompts []SystemPromptPolicy `json:"system_prompts,omitempty"`
+
+	// ContextWindows overrides or adds context window sizes in tokens,
+	// keyed by model-name prefix.
+	ContextWindows map[string]int64 `json:"context_windows,omitempty"`
+
+	// KeyPools replaces the single API key of a provider, keyed by provider
+	// name, with a pool of keys and deployments.
+	KeyPools map[string]KeyPool `json:"key_pools,omitempty"`
+
+	Routing RoutingConfig `json:"routing,omitempty"`
+
+	AutoModel *AutoModelConfig `json:"auto_model,omitempty"`
+
+	Hedging []HedgePolicy `json:"hedging,omitempty"`
+
+	// Shadow mirrors sampled traffic to shadow models; comparisons are
+	// stored in the SQLite database at SHADOW_PATH.
+	Shadow []ShadowPolicy `json:"shadow,omitempty"`
+
+	Experiments []Experiment `json:"experiments,omitempty"`
+
+	Ensembles []Ensemble `json:"ensembles,omitempty"`
+}
+
+// Match selects requests by client key, HTTP route or requested model. An
+// empty list matches anything. Keys may be raw API keys or the "key-..."
+// fingerprints that appear in logs; Models accept path.Match globs.
+type Match struct {
+	Keys   []string `json:"keys,omitempty"`
+	Routes []string `json:"routes,omitempty"`
+	Models []string `json:"models,omitempty"`
+}
+
+func (m Match) Matches(meta requestmeta.Meta, model string) bool {
+	return matchAny(m.Keys, meta.Key, func(key string) string {
+		if strings.HasPrefix(key, "key-") {
+			return key
+		}
+		return requestmeta.FingerprintKey(key)
+	}) &&
+		matchAny(m.Routes, meta.Route, nil) &&
+		matchAny(m.Models, model, nil)
+}
+
+func matchAny(patterns []string, value string, normalize func(string) string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if normalize != nil {
+			pattern = normalize(pattern)
+		}
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PIIPolicy lists the entity types redacted for matching requests. The first
+// matching policy applies.
+type PIIPolicy struct {
+	Match    Match             `json:"match"`
+	Entities []string          `json:"entities"`
+	Custom   []CustomPIIEntity `json:"custom,omitempty"`
+}
+
+type CustomPIIEntity struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// GuardrailPolicy lists the guardrails run for matching requests. The first
+// matching policy applies.
+type GuardrailPolicy struct {
+	Match Match           `json:"match"`
+	Rules []GuardrailRule `json:"rules"`
+}
+
+// GuardrailRule configures one guardrail. Type is prompt_injection,
+// blocklist, banned_topic, max_output_length or hook; Action is block
+// (default), flag or rewrite.
+type GuardrailRule struct {
+	Type       string   `json:"type"`
+	Name       string   `json:"name,omitempty"`
+	Action     string   `json:"action,omitempty"`
+	Stages     []string `json:"stages,omitempty"`
+	Terms      []string `json:"terms,omitempty"`
+	MaxChars   int      `json:"max_chars,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	TimeoutMS  int      `json:"timeout_ms,omitempty"`
+	FailClosed bool     `json:"fail_closed,omitempty"`
+}
+
+// PluginConfig names a registered plugin and its plugin-specific options.
+type PluginConfig struct {
+	Name    string          `json:"name"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// PromptTemplate is one version of a named prompt template. Message
+// contents use Go text/template syntax.
+type PromptTemplate struct {
+	ID       string            `json:"id"`
+	Version  int               `json:"version"`
+	Messages []TemplateMessage `json:"messages"`
+}
+
+type TemplateMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SystemPromptPolicy prepends or enforces a system prompt on matching
+// requests. The first matching policy applies.
+type SystemPromptPolicy struct {
+	Match   Match  `json:"match"`
+	Mode    string `json:"mode"`
+	Content string `json:"content"`
+}
+
+// KeyPool balances a provider's requests across Members. A member is
+// cooled down for CooldownSeconds (default 30) after a 429, unless the
+// upstream sends Retry-After, or after FailureThreshold (default 3)
+// consecutive failures.
+type KeyPool struct {
+	Members          []KeyPoolMember `json:"members"`
+	CooldownSeconds  int             `json:"cooldown_seconds,omitempty"`
+	FailureThreshold int             `json:"failure_threshold,omitempty"`
+}
+
+// KeyPoolMember is one key or deployment. APIKeyEnv names an environment
+// variable holding the key, so the file need not contain secrets.
+type KeyPoolMember struct {
+	Name        string  `json:"name,omitempty"`
+	APIKey      string  `json:"api_key,omitempty"`
+	APIKeyEnv   string  `json:"api_key_env,omitempty"`
+	BaseURL     string  `json:"base_url,omitempty"`
+	Weight      float64 `json:"weight,omitempty"`
+	MaxInFlight int     `json:"max_in_flight,omitempty"`
+}
+
+func (m KeyPoolMember) Key() string {
+	if m.APIKeyEnv != "" {
+		return os.Getenv(m.APIKeyEnv)
+	}
+	return m.APIKey
+}
+
+// RoutingConfig defines model aliases served by whichever target best fits
+// the route's objective. Observations older than WindowSeconds (default
+// 300) are forgotten; a target needs MinSamples (default 20) before its
+// constraints apply; ExploreRate (default 0.05) of requests try a random
+// target.
+type RoutingConfig struct {
+	Routes        []RouteConfig `json:"routes,omitempty"`
+	WindowSeconds int           `json:"window_seconds,omitempty"`
+	MinSamples    int           `json:"min_samples,omitempty"`
+	ExploreRate   *float64      `json:"explore_rate,omitempty"`
+}
+
+// RouteConfig serves Alias with one of Targets. Optimize is cost (default)
+// or latency; MaxP95MS and MaxErrorRate (default 0.5, 1 to disable)
+// exclude targets that exceed them, so "cheapest under 2s p95" is optimize
+// cost with max_p95_ms 2000.
+type RouteConfig struct {
+	Alias        string   `json:"alias"`
+	Targets      []string `json:"targets"`
+	Optimize     string   `json:"optimize,omitempty"`
+	MaxP95MS     int      `json:"max_p95_ms,omitempty"`
+	MaxErrorRate float64  `json:"max_error_rate,omitempty"`
+}
+
+// AutoModelConfig defines a virtual model, "auto" by default, that sends
+// each request to CheapModel or StrongModel. Requests scoring Threshold
+// (default 3) or more on the heuristic go to the strong model; prompts of
+// LongPromptTokens (default 2000) count as long. With ClassifierModel set,
+// that model makes the call instead and the heuristic is the fallback.
+type AutoModelConfig struct {
+	Model               string `json:"model,omitempty"`
+	CheapModel          string `json:"cheap_model"`
+	StrongModel         string `json:"strong_model"`
+	Threshold           int    `json:"threshold,omitempty"`
+	LongPromptTokens    int64  `json:"long_prompt_tokens,omitempty"`
+	ClassifierModel     string `json:"classifier_model,omitempty"`
+	ClassifierTimeoutMS int    `json:"classifier_timeout_ms,omitempty"`
+}
+
+// HedgePolicy sends a backup request, to BackupModel or to the same model
+// when empty, for matching requests that have not produced a first token
+// after DelayMS. The first matching policy applies.
+type HedgePolicy struct {
+	Match       Match  `json:"match"`
+	DelayMS     int    `json:"delay_ms"`
+	BackupModel string `json:"backup_model,omitempty"`
+}
+
+// ShadowPolicy mirrors SampleRate (0 to 1) of matching requests to Model.
+// The first matching policy applies.
+type ShadowPolicy struct {
+	Match      Match   `json:"match"`
+	Model      string  `json:"model"`
+	SampleRate float64 `json:"sample_rate"`
+}
+
+// Experiment splits matching requests between Arms by weight. Clients are
+// assigned by a hash of their key, or of the request's user field when
+// StickyBy is "user", so they stay on one arm. To roll out a canary, list it
+// last and raise its weight. The first matching experiment applies.
+type Experiment struct {
+	ID       string          `json:"id"`
+	Match    Match           `json:"match"`
+	StickyBy string          `json:"sticky_by,omitempty"`
+	Arms     []ExperimentArm `json:"arms"`
+}
+
+type ExperimentArm struct {
+	Name   string  `json:"name"`
+	Model  string  `json:"model"`
+	Weight float64 `json:"weight"`
+}
+
+// Ensemble defines a virtual model that runs every candidate in parallel
+// and answers with the one JudgeModel picks, or with the consensus answer
+// when there is no judge or it fails. IncludeCandidates adds every
+// candidate's output to non-streaming replies. Candidates still running
+// after TimeoutMS are left out.
+type Ensemble struct {
+	Model             string   `json:"model"`
+	Candidates        []string `json:"candidates"`
+	JudgeModel        string   `json:"judge_model,omitempty"`
+	IncludeCandidates bool     `json:"include_candidates,omitempty"`
+	TimeoutMS         int      `json:"timeout_ms,omitempty"`
+}
+
+func loadFileConfig(filename string) (FileConfig, error) {
+	var fc FileConfig
+	if filename == "" {
+		return fc, nil
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fc, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return fc, fmt.Errorf("failed to parse config file %s: %w", filename, err)
+	}
+	return fc, nil
+}
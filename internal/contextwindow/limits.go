@@ -0,0 +1,40 @@
+package contextwindow
+
+import "strings"
+
+// windows holds each model family's context window in tokens, matched by
+// longest model-name prefix like the price table.
+var windows = map[string]int64{
+	"gpt-4o-mini":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"gpt-4-turbo":   128000,
+	"gpt-4":         8192,
+	"gpt-3.5-turbo": 16385,
+	"claude-3":      200000,
+	"claude-sonnet": 200000,
+	"claude-opus":   200000,
+}
+
+// Limit returns the context window of model. Overrides take precedence over
+// the built-in table and are matched the same way.
+func Limit(model string, overrides map[string]int64) (int64, bool) {
+	if limit, ok := longestPrefix(model, overrides); ok {
+		return limit, true
+	}
+	return longestPrefix(model, windows)
+}
+
+func longestPrefix(model string, table map[string]int64) (int64, bool) {
+	model = strings.ToLower(model)
+	var best string
+	for prefix := range table {
+		if strings.HasPrefix(model, strings.ToLower(prefix)) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return 0, false
+	}
+	return table[best], true
+}
@@ -0,0 +1,101 @@
+package contextwindow
+
+import (
+	"fmt"
+
+	"github.com/llm-router/internal/models"
+)
+
+// Strategy decides which messages go when a conversation is too long.
+// System messages and the final message are always kept.
+type Strategy string
+
+const (
+	// DropOldest removes the oldest turns first.
+	DropOldest Strategy = "drop_oldest"
+	// KeepLast keeps the system messages and the last N other messages,
+	// then drops the oldest of those if they still do not fit.
+	KeepLast Strategy = "keep_last"
+	// MiddleOut removes turns from the middle of the conversation, keeping
+	// its beginning and end.
+	MiddleOut Strategy = "middle_out"
+	// Summarize replaces the turns DropOldest would remove with a summary.
+	Summarize Strategy = "summarize"
+)
+
+func ParseStrategy(s string) (Strategy, bool) {
+	switch strategy := Strategy(s); strategy {
+	case DropOldest, KeepLast, MiddleOut, Summarize:
+		return strategy, true
+	}
+	return "", false
+}
+
+// messageOverhead approximates the tokens each message costs for its role
+// and framing on top of its content.
+const messageOverhead = 4
+
+// Counter returns the number of tokens in text.
+type Counter func(text string) int64
+
+func Count(messages []models.Message, count Counter) int64 {
+	var total int64
+	for _, msg := range messages {
+		total += count(msg.Content) + messageOverhead
+	}
+	return total
+}
+
+// Trim returns messages cut down to budget tokens using strategy, along
+// with the messages that were removed in their original order. Summarize
+// trims like DropOldest; the caller summarizes what was removed.
+func Trim(messages []models.Message, budget int64, strategy Strategy, keepLast int, count Counter) (kept, removed []models.Message, err error) {
+	tokens := Count(messages, count)
+	if tokens <= budget {
+		return messages, nil, nil
+	}
+
+	// Only messages between the system prompt and the final message are
+	// candidates; drop marks the ones removed.
+	var candidates []int
+	for i, msg := range messages[:len(messages)-1] {
+		if msg.Role != "system" {
+			candidates = append(candidates, i)
+		}
+	}
+	drop := make(map[int]bool)
+	remove := func(i int) {
+		drop[i] = true
+		tokens -= count(messages[i].Content) + messageOverhead
+	}
+
+	if strategy == KeepLast {
+		// The final message counts towards N.
+		for len(candidates) > 0 && len(candidates) > keepLast-1 {
+			remove(candidates[0])
+			candidates = candidates[1:]
+		}
+	}
+
+	for tokens > budget && len(candidates) > 0 {
+		next := 0
+		if strategy == MiddleOut {
+			next = len(candidates) / 2
+		}
+		remove(candidates[next])
+		candidates = append(candidates[:next], candidates[next+1:]...)
+	}
+
+	if tokens > budget {
+		return nil, nil, fmt.Errorf("messages need %d tokens after trimming, more than the %d available", tokens, budget)
+	}
+
+	for i, msg := range messages {
+		if drop[i] {
+			removed = append(removed, msg)
+		} else {
+			kept = append(kept, msg)
+		}
+	}
+	return kept, removed, nil
+}
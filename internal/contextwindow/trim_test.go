@@ -0,0 +1,148 @@
+package contextwindow
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/llm-router/internal/models"
+)
+
+// words counts a token per word, so a one-word message costs 1 token plus
+// messageOverhead.
+func words(text string) int64 {
+	return int64(len(strings.Fields(text)))
+}
+
+func conversation(contents ...string) []models.Message {
+	var messages []models.Message
+	for _, c := range contents {
+		role := "user"
+		switch {
+		case strings.HasPrefix(c, "sys"):
+			role = "system"
+		case strings.HasPrefix(c, "a"):
+			role = "assistant"
+		}
+		messages = append(messages, models.Message{Role: role, Content: c})
+	}
+	return messages
+}
+
+func contents(messages []models.Message) []string {
+	var out []string
+	for _, m := range messages {
+		out = append(out, m.Content)
+	}
+	return out
+}
+
+func TestTrim(t *testing.T) {
+	// Each message costs 5 tokens; the whole conversation 30.
+	long := conversation("sys", "u1", "a1", "u2", "a2", "u3")
+	tests := []struct {
+		name     string
+		messages []models.Message
+		budget   int64
+		strategy Strategy
+		keepLast int
+		kept     []string
+		removed  []string
+		err      bool
+	}{
+		{
+			name:     "fits",
+			messages: long,
+			budget:   30,
+			strategy: DropOldest,
+			kept:     []string{"sys", "u1", "a1", "u2", "a2", "u3"},
+		},
+		{
+			name:     "drop oldest",
+			messages: long,
+			budget:   20,
+			strategy: DropOldest,
+			kept:     []string{"sys", "u2", "a2", "u3"},
+			removed:  []string{"u1", "a1"},
+		},
+		{
+			name:     "summarize drops like drop oldest",
+			messages: long,
+			budget:   24,
+			strategy: Summarize,
+			kept:     []string{"sys", "u2", "a2", "u3"},
+			removed:  []string{"u1", "a1"},
+		},
+		{
+			name:     "middle out",
+			messages: long,
+			budget:   20,
+			strategy: MiddleOut,
+			kept:     []string{"sys", "u1", "a2", "u3"},
+			removed:  []string{"a1", "u2"},
+		},
+		{
+			name:     "keep last",
+			messages: long,
+			budget:   29,
+			strategy: KeepLast,
+			keepLast: 3,
+			kept:     []string{"sys", "u2", "a2", "u3"},
+			removed:  []string{"u1", "a1"},
+		},
+		{
+			name:     "keep last still too long",
+			messages: long,
+			budget:   10,
+			strategy: KeepLast,
+			keepLast: 3,
+			kept:     []string{"sys", "u3"},
+			removed:  []string{"u1", "a1", "u2", "a2"},
+		},
+		{
+			name:     "system messages stay in place",
+			messages: conversation("sys", "u1", "sys2", "a1", "u2"),
+			budget:   15,
+			strategy: DropOldest,
+			kept:     []string{"sys", "sys2", "u2"},
+			removed:  []string{"u1", "a1"},
+		},
+		{
+			name:     "system and final message do not fit",
+			messages: long,
+			budget:   9,
+			strategy: DropOldest,
+			err:      true,
+		},
+		{
+			name:     "final message alone does not fit",
+			messages: conversation("u1 is far too long"),
+			budget:   5,
+			strategy: MiddleOut,
+			err:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, removed, err := Trim(tt.messages, tt.budget, tt.strategy, tt.keepLast, words)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("kept %v, want an error", contents(kept))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := contents(kept); !reflect.DeepEqual(got, tt.kept) {
+				t.Errorf("kept %v, want %v", got, tt.kept)
+			}
+			if got := contents(removed); !reflect.DeepEqual(got, tt.removed) {
+				t.Errorf("removed %v, want %v", got, tt.removed)
+			}
+			if Count(kept, words) > tt.budget {
+				t.Errorf("kept %d tokens, more than the budget of %d", Count(kept, words), tt.budget)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package ensemble
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/llm-router/internal/models"
+)
+
+// Strategies for picking the answer of an ensemble.
+const (
+	StrategyJudge     = "judge"
+	StrategyConsensus = "consensus"
+)
+
+const judgePrompt = "You compare candidate answers to the same conversation. " +
+	"Pick the answer that best serves the user: correct, complete and following their instructions. " +
+	"Reply with only the number of the best answer."
+
+// judgeInputChars bounds how much of the conversation, the latest part,
+// and of each answer, its start, the judge sees.
+const judgeInputChars = 8000
+
+// Consensus returns the index of the answer that agrees most with the
+// others, scored by word overlap. Identical answers reinforce each other,
+// so a majority wins; ties go to the earliest answer.
+func Consensus(answers []string) int {
+	sets := make([]map[string]struct{}, len(answers))
+	for i, a := range answers {
+		sets[i] = words(a)
+	}
+
+	best, bestScore := 0, -1.0
+	for i := range sets {
+		var score float64
+		for j := range sets {
+			if i != j {
+				score += jaccard(sets[i], sets[j])
+			}
+		}
+		if score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func words(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		set[w] = struct{}{}
+	}
+	return set
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	var shared int
+	for w := range a {
+		if _, ok := b[w]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// JudgeMessages builds the request asking a judge model to pick among
+// answers to conversation.
+func JudgeMessages(conversation []models.Message, answers []string) []models.Message {
+	var transcript strings.Builder
+	for _, m := range conversation {
+		fmt.Fprintf(&transcript, "%s: %s\n", m.Role, m.Content)
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString("Conversation:\n")
+	prompt.WriteString(tail(transcript.String(), judgeInputChars))
+	for i, a := range answers {
+		fmt.Fprintf(&prompt, "\nAnswer %d:\n%s\n", i+1, head(a, judgeInputChars))
+	}
+
+	return []models.Message{
+		{Role: "system", Content: judgePrompt},
+		{Role: "user", Content: prompt.String()},
+	}
+}
+
+var numberPattern = regexp.MustCompile(`\d+`)
+
+// ParseVerdict returns the index of the answer the judge picked out of n.
+func ParseVerdict(reply string, n int) (int, error) {
+	match := numberPattern.FindString(reply)
+	if match == "" {
+		return 0, fmt.Errorf("judge reply %q names no answer", reply)
+	}
+	pick, err := strconv.Atoi(match)
+	if err != nil || pick < 1 || pick > n {
+		return 0, fmt.Errorf("judge picked answer %s of %d", match, n)
+	}
+	return pick - 1, nil
+}
+
+// head returns the first n characters of s.
+func head(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n])
+	}
+	return s
+}
+
+// tail returns the last n characters of s.
+func tail(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[len(runes)-n:])
+	}
+	return s
+}
@@ -0,0 +1,73 @@
+package ensemble
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/llm-router/internal/models"
+)
+
+func TestParseVerdict(t *testing.T) {
+	tests := []struct {
+		reply string
+		n     int
+		want  int
+		ok    bool
+	}{
+		{"2", 3, 1, true},
+		{"1", 1, 0, true},
+		{" 3\n", 3, 2, true},
+		{"Answer 2 is best.", 3, 1, true},
+		{"**2**", 3, 1, true},
+		{"2, then 1", 3, 1, true},
+		{"10", 12, 9, true},
+		{"0", 3, 0, false},
+		{"4", 3, 0, false},
+		{"99999999999999999999", 3, 0, false},
+		{"the second one", 3, 0, false},
+		{"", 3, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseVerdict(tt.reply, tt.n)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("ParseVerdict(%q, %d) = %d, %v; want %d", tt.reply, tt.n, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("ParseVerdict(%q, %d) = %d, want an error", tt.reply, tt.n, got)
+		}
+	}
+}
+
+func TestConsensus(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string
+		want    int
+	}{
+		{"single", []string{"Paris"}, 0},
+		{"majority", []string{"It is Lyon.", "It is Paris.", "It is Paris!"}, 1},
+		{"word overlap", []string{"blue sky", "the sky is blue today", "the sky is blue"}, 2},
+		{"ties go first", []string{"yes", "no"}, 0},
+		{"empty answers agree", []string{"", "", "something"}, 0},
+	}
+	for _, tt := range tests {
+		if got := Consensus(tt.answers); got != tt.want {
+			t.Errorf("%s: Consensus(%q) = %d, want %d", tt.name, tt.answers, got, tt.want)
+		}
+	}
+}
+
+func TestJudgeMessagesTrimsOnCharacters(t *testing.T) {
+	long := strings.Repeat("é", judgeInputChars+1)
+	messages := JudgeMessages([]models.Message{{Role: "user", Content: long}}, []string{long})
+
+	prompt := messages[1].Content
+	if !utf8.ValidString(prompt) {
+		t.Fatal("judge prompt is not valid UTF-8")
+	}
+	// The conversation's tail ends with the newline after its last message.
+	if want := 2*judgeInputChars - 1; strings.Count(prompt, "é") != want {
+		t.Errorf("judge prompt keeps %d characters of the input, want %d", strings.Count(prompt, "é"), want)
+	}
+}
@@ -0,0 +1,86 @@
+package experiments
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// StickyBy values choose what keeps a client on the same arm.
+const (
+	StickyByKey  = "key"
+	StickyByUser = "user"
+)
+
+// Arm is one side of an experiment. Weights are relative, so 90 and 10
+// split traffic 90%/10%.
+type Arm struct {
+	Name   string
+	Model  string
+	Weight float64
+}
+
+// Experiment splits matching requests between Arms. Assignment hashes the
+// experiment ID with the client's key or the request's user field, so a
+// client stays on one arm for as long as the weights are unchanged. Arms
+// own consecutive slices of the hash space in order, so growing the last
+// arm of a canary only moves clients onto it, never between other arms.
+type Experiment struct {
+	ID       string
+	Matches  func(meta requestmeta.Meta, model string) bool
+	StickyBy string
+	Arms     []Arm
+
+	total float64
+}
+
+func New(id string, matches func(meta requestmeta.Meta, model string) bool, stickyBy string, arms []Arm) (*Experiment, error) {
+	if id == "" {
+		return nil, fmt.Errorf("experiment needs an id")
+	}
+	switch stickyBy {
+	case "":
+		stickyBy = StickyByKey
+	case StickyByKey, StickyByUser:
+	default:
+		return nil, fmt.Errorf("experiment %s: unknown sticky_by %q", id, stickyBy)
+	}
+	if len(arms) == 0 {
+		return nil, fmt.Errorf("experiment %s has no arms", id)
+	}
+
+	e := &Experiment{ID: id, Matches: matches, StickyBy: stickyBy, Arms: arms}
+	for _, arm := range arms {
+		if arm.Name == "" || arm.Model == "" || arm.Weight < 0 {
+			return nil, fmt.Errorf("experiment %s: every arm needs a name, a model and a non-negative weight", id)
+		}
+		e.total += arm.Weight
+	}
+	if e.total == 0 {
+		return nil, fmt.Errorf("experiment %s: arm weights add up to zero", id)
+	}
+	return e, nil
+}
+
+// Assign returns the arm for unit, the value the experiment is sticky by.
+func (e *Experiment) Assign(unit string) Arm {
+	sum := sha256.Sum256([]byte(e.ID + ":" + unit))
+	point := float64(binary.BigEndian.Uint64(sum[:8])) / (1 << 64) * e.total
+
+	var upper float64
+	last := 0
+	for i, arm := range e.Arms {
+		upper += arm.Weight
+		if point < upper {
+			return arm
+		}
+		if arm.Weight > 0 {
+			last = i
+		}
+	}
+	// Rounding can put point at the very top of the range; it belongs to
+	// the last arm with any weight.
+	return e.Arms[last]
+}
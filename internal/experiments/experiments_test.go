@@ -0,0 +1,138 @@
+package experiments
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// weighted returns arms arm-0, arm-1, ... on model-0, model-1, ... with the
+// given weights.
+func weighted(weights ...float64) []Arm {
+	var arms []Arm
+	for i, w := range weights {
+		arms = append(arms, Arm{Name: fmt.Sprintf("arm-%d", i), Model: fmt.Sprintf("model-%d", i), Weight: w})
+	}
+	return arms
+}
+
+func TestNew(t *testing.T) {
+	arm := Arm{Name: "control", Model: "gpt-4o", Weight: 1}
+	tests := []struct {
+		name     string
+		id       string
+		stickyBy string
+		arms     []Arm
+		ok       bool
+	}{
+		{"valid", "exp", "", []Arm{arm}, true},
+		{"sticky by user", "exp", StickyByUser, []Arm{arm}, true},
+		{"no id", "", "", []Arm{arm}, false},
+		{"unknown sticky_by", "exp", "session", []Arm{arm}, false},
+		{"no arms", "exp", "", nil, false},
+		{"arm without model", "exp", "", []Arm{{Name: "control", Weight: 1}}, false},
+		{"negative weight", "exp", "", []Arm{arm, {Name: "b", Model: "gpt-4o-mini", Weight: -1}}, false},
+		{"zero total weight", "exp", "", []Arm{{Name: "control", Model: "gpt-4o"}}, false},
+	}
+	for _, tt := range tests {
+		e, err := New(tt.id, nil, tt.stickyBy, tt.arms)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: got %+v, want an error", tt.name, e)
+		}
+	}
+	if e, _ := New("exp", nil, "", []Arm{arm}); e.StickyBy != StickyByKey {
+		t.Errorf("default sticky_by = %q, want %q", e.StickyBy, StickyByKey)
+	}
+}
+
+func TestAssign(t *testing.T) {
+	const units = 20000
+	tests := []struct {
+		name    string
+		weights []float64
+	}{
+		{"single arm", []float64{1}},
+		{"even split", []float64{50, 50}},
+		{"canary", []float64{90, 10}},
+		{"three arms", []float64{1, 2, 1}},
+		{"zero weight arm", []float64{70, 0, 30}},
+		{"zero weight last arm", []float64{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := New("exp", nil, "", weighted(tt.weights...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			counts := make(map[string]int)
+			for i := range units {
+				unit := fmt.Sprintf("key-%d", i)
+				arm := e.Assign(unit)
+				if again := e.Assign(unit); again != arm {
+					t.Fatalf("%s assigned %s, then %s", unit, arm.Name, again.Name)
+				}
+				counts[arm.Name]++
+			}
+
+			var total float64
+			for _, w := range tt.weights {
+				total += w
+			}
+			for i, w := range tt.weights {
+				name := fmt.Sprintf("arm-%d", i)
+				share := float64(counts[name]) / units
+				if w == 0 && counts[name] > 0 {
+					t.Errorf("zero weight %s got %d units", name, counts[name])
+				}
+				if math.Abs(share-w/total) > 0.02 {
+					t.Errorf("%s got %.3f of units, want %.3f", name, share, w/total)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignGrowingCanaryOnlyMovesOntoIt(t *testing.T) {
+	before, errBefore := New("exp", nil, "", weighted(95, 5))
+	after, errAfter := New("exp", nil, "", weighted(90, 10))
+	if err := errors.Join(errBefore, errAfter); err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for i := range 20000 {
+		unit := fmt.Sprintf("key-%d", i)
+		was, is := before.Assign(unit), after.Assign(unit)
+		if was.Name == "arm-1" && is.Name != "arm-1" {
+			t.Fatalf("%s moved off the canary", unit)
+		}
+		if was != is {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("no unit moved onto the grown canary")
+	}
+}
+
+func TestAssignDependsOnExperiment(t *testing.T) {
+	a, errA := New("exp-a", nil, "", weighted(50, 50))
+	b, errB := New("exp-b", nil, "", weighted(50, 50))
+	if err := errors.Join(errA, errB); err != nil {
+		t.Fatal(err)
+	}
+	same := 0
+	for i := range 20000 {
+		unit := fmt.Sprintf("key-%d", i)
+		if a.Assign(unit) == b.Assign(unit) {
+			same++
+		}
+	}
+	// Independent experiments agree on about half the units.
+	if share := float64(same) / 20000; math.Abs(share-0.5) > 0.02 {
+		t.Errorf("experiments agree on %.3f of units, want about 0.5", share)
+	}
+}
@@ -0,0 +1,118 @@
+package guardrails
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Stage says which side of the provider call is being checked.
+type Stage string
+
+const (
+	StageInput  Stage = "input"
+	StageOutput Stage = "output"
+)
+
+// Action is what a guardrail wants done with the content it checked.
+type Action string
+
+const (
+	ActionAllow   Action = ""
+	ActionFlag    Action = "flag"
+	ActionRewrite Action = "rewrite"
+	ActionBlock   Action = "block"
+)
+
+func ParseAction(s string) (Action, bool) {
+	switch a := Action(s); a {
+	case ActionFlag, ActionRewrite, ActionBlock:
+		return a, true
+	}
+	return ActionAllow, false
+}
+
+// Content is a piece of text to check. For output, Text is everything the
+// model has produced so far for one choice, and Final is set once the choice
+// has finished.
+type Content struct {
+	Stage Stage
+	Role  string
+	Text  string
+	Model string
+	Route string
+	Final bool
+}
+
+// Verdict is the outcome of a check. Rewrite holds the replacement text when
+// Action is ActionRewrite.
+type Verdict struct {
+	Action  Action
+	Rule    string
+	Reason  string
+	Rewrite string
+}
+
+type Guardrail interface {
+	Name() string
+	Check(ctx context.Context, content Content) (Verdict, error)
+}
+
+// local is implemented by guardrails that run in-process and are cheap
+// enough to run on every chunk of a stream.
+type local interface {
+	local()
+}
+
+// Local returns the guardrails among gs that are cheap enough to run on
+// every streamed chunk. The others only need to see output in larger steps.
+func Local(gs []Guardrail) []Guardrail {
+	var out []Guardrail
+	for _, g := range gs {
+		if _, ok := g.(local); ok {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// Result combines the verdicts of several guardrails. Text is the content
+// after any rewrites; Blocked is set by the first blocking verdict.
+type Result struct {
+	Text    string
+	Blocked *Verdict
+	Flags   []Verdict
+}
+
+// Evaluate runs guardrails in order. Rewrites feed into the next guardrail
+// and evaluation stops at the first block. A guardrail that fails is logged
+// and skipped; guardrails that must fail closed return a block instead.
+func Evaluate(ctx context.Context, guardrails []Guardrail, content Content, logger *slog.Logger) Result {
+	result := Result{Text: content.Text}
+	for _, g := range guardrails {
+		content.Text = result.Text
+		verdict, err := g.Check(ctx, content)
+		if err != nil {
+			logger.WarnContext(ctx, "guardrail check failed",
+				slog.String("guardrail", g.Name()),
+				slog.String("stage", string(content.Stage)),
+				slog.Any("error", err),
+			)
+			continue
+		}
+		if verdict.Rule == "" {
+			verdict.Rule = g.Name()
+		}
+
+		switch verdict.Action {
+		case ActionBlock:
+			result.Blocked = &verdict
+			return result
+		case ActionRewrite:
+			result.Text = verdict.Rewrite
+			result.Flags = append(result.Flags, verdict)
+		case ActionFlag:
+			result.Flags = append(result.Flags, verdict)
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package guardrails
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type hook struct {
+	name       string
+	url        string
+	client     *http.Client
+	failClosed bool
+}
+
+// NewHook delegates checks to an external HTTP service. Each check POSTs the
+// Content as JSON and expects a Verdict back, e.g.
+// {"action": "block", "reason": "..."}; an empty action allows. Streamed
+// output is only sent once it is complete. Unless failClosed is set, an
+// unreachable or failing hook allows the content.
+func NewHook(name, url string, timeout time.Duration, failClosed bool) Guardrail {
+	return &hook{
+		name:       name,
+		url:        url,
+		client:     &http.Client{Timeout: timeout},
+		failClosed: failClosed,
+	}
+}
+
+func (h *hook) Name() string {
+	return h.name
+}
+
+type hookRequest struct {
+	Stage Stage  `json:"stage"`
+	Role  string `json:"role,omitempty"`
+	Text  string `json:"text"`
+	Model string `json:"model"`
+	Route string `json:"route"`
+}
+
+type hookResponse struct {
+	Action  Action `json:"action"`
+	Reason  string `json:"reason"`
+	Rewrite string `json:"rewrite"`
+}
+
+func (h *hook) Check(ctx context.Context, content Content) (Verdict, error) {
+	if content.Stage == StageOutput && !content.Final {
+		return Verdict{}, nil
+	}
+
+	verdict, err := h.call(ctx, content)
+	if err != nil && h.failClosed {
+		return Verdict{Action: ActionBlock, Reason: "guardrail hook unavailable"}, nil
+	}
+	return verdict, err
+}
+
+func (h *hook) call(ctx context.Context, content Content) (Verdict, error) {
+	body, err := json.Marshal(hookRequest{
+		Stage: content.Stage,
+		Role:  content.Role,
+		Text:  content.Text,
+		Model: content.Model,
+		Route: content.Route,
+	})
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("guardrail hook %s returned %s", h.name, resp.Status)
+	}
+
+	var hr hookResponse
+	if err := json.NewDecoder(resp.Body).Decode(&hr); err != nil {
+		return Verdict{}, fmt.Errorf("failed to decode guardrail hook response: %w", err)
+	}
+	if _, ok := ParseAction(string(hr.Action)); !ok && hr.Action != ActionAllow {
+		return Verdict{}, fmt.Errorf("guardrail hook %s returned unknown action %q", h.name, hr.Action)
+	}
+	return Verdict{Action: hr.Action, Reason: hr.Reason, Rewrite: hr.Rewrite}, nil
+}
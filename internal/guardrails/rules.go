@@ -0,0 +1,131 @@
+package guardrails
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// rewriteMask replaces matched text when a pattern rule rewrites.
+const rewriteMask = "[filtered]"
+
+// patternRule triggers when any of its patterns match.
+type patternRule struct {
+	name     string
+	patterns []*regexp.Regexp
+	action   Action
+	stages   []Stage
+	roles    []string
+}
+
+func (r *patternRule) Name() string {
+	return r.name
+}
+
+func (r *patternRule) local() {}
+
+func (r *patternRule) Check(ctx context.Context, content Content) (Verdict, error) {
+	if len(r.stages) > 0 && !slices.Contains(r.stages, content.Stage) {
+		return Verdict{}, nil
+	}
+	if len(r.roles) > 0 && !slices.Contains(r.roles, content.Role) {
+		return Verdict{}, nil
+	}
+
+	for _, pattern := range r.patterns {
+		match := pattern.FindString(content.Text)
+		if match == "" {
+			continue
+		}
+		verdict := Verdict{
+			Action: r.action,
+			Reason: fmt.Sprintf("matched %q", match),
+		}
+		if r.action == ActionRewrite {
+			text := content.Text
+			for _, p := range r.patterns {
+				text = p.ReplaceAllString(text, rewriteMask)
+			}
+			verdict.Rewrite = text
+		}
+		return verdict, nil
+	}
+	return Verdict{}, nil
+}
+
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget)\s+(all\s+|any\s+)?(the\s+|your\s+)?(previous|prior|above|earlier|preceding)\s+(instructions|prompts?|rules|directions)`),
+	regexp.MustCompile(`(?i)\b(reveal|print|show|repeat|output)\s+(me\s+)?(your|the)\s+(system\s+prompt|hidden\s+instructions|initial\s+instructions)`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+now\s+(in\s+)?(DAN|developer\s+mode|jailbroken|unrestricted)`),
+	regexp.MustCompile(`(?i)\b(pretend|act\s+as\s+if)\s+you\s+(have\s+no|are\s+not\s+bound\s+by\s+any)\s+(restrictions|rules|guidelines)`),
+	regexp.MustCompile(`(?i)^\s*(system|assistant)\s*:`),
+}
+
+// NewPromptInjection flags user messages that look like attempts to override
+// the system prompt.
+func NewPromptInjection(action Action) Guardrail {
+	return &patternRule{
+		name:     "prompt_injection",
+		patterns: injectionPatterns,
+		action:   action,
+		stages:   []Stage{StageInput},
+		roles:    []string{"user"},
+	}
+}
+
+// NewBlocklist triggers on any of terms as a whole word, ignoring case. It
+// serves both keyword blocklists and banned topics, which are named lists of
+// terms. With no stages it checks input and output.
+func NewBlocklist(name string, terms []string, action Action, stages []Stage) (Guardrail, error) {
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("guardrail %s: no terms", name)
+	}
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	pattern, err := regexp.Compile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	if err != nil {
+		return nil, fmt.Errorf("guardrail %s: %w", name, err)
+	}
+	return &patternRule{
+		name:     name,
+		patterns: []*regexp.Regexp{pattern},
+		action:   action,
+		stages:   stages,
+	}, nil
+}
+
+type maxLength struct {
+	chars  int
+	action Action
+}
+
+// NewMaxLength limits the length of model output in characters. Rewriting
+// truncates the output at the limit.
+func NewMaxLength(chars int, action Action) Guardrail {
+	return &maxLength{chars: chars, action: action}
+}
+
+func (m *maxLength) Name() string {
+	return "max_output_length"
+}
+
+func (m *maxLength) local() {}
+
+func (m *maxLength) Check(ctx context.Context, content Content) (Verdict, error) {
+	if content.Stage != StageOutput || utf8.RuneCountInString(content.Text) <= m.chars {
+		return Verdict{}, nil
+	}
+	verdict := Verdict{
+		Action: m.action,
+		Reason: fmt.Sprintf("output longer than %d characters", m.chars),
+	}
+	if m.action == ActionRewrite {
+		verdict.Rewrite = string([]rune(content.Text)[:m.chars])
+	}
+	return verdict, nil
+}
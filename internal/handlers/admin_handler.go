@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/llm-router/internal/routing"
+)
+
+type AdminHandler struct {
+	router *routing.Router
+}
+
+func NewAdminHandler(router *routing.Router) *AdminHandler {
+	return &AdminHandler{
+		router: router,
+	}
+}
+
+// HandleRoutingScores reports the live scores behind dynamic routing.
+func (h *AdminHandler) HandleRoutingScores(c *gin.Context) {
+	var routes []routing.RouteScores
+	if h.router != nil {
+		routes = h.router.Scores()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object": "list",
+		"data":   routes,
+	})
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/llm-router/internal/batches"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+type BatchHandler struct {
+	store        *batches.Store
+	runner       *batches.Runner
+	maxFileBytes int64
+	logger       *slog.Logger
+}
+
+func NewBatchHandler(store *batches.Store, runner *batches.Runner, maxFileBytes int64, logger *slog.Logger) *BatchHandler {
+	return &BatchHandler{
+		store:        store,
+		runner:       runner,
+		maxFileBytes: maxFileBytes,
+		logger:       logger,
+	}
+}
+
+type createBatchRequest struct {
+	InputFileID      string            `json:"input_file_id"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+func (h *BatchHandler) HandleUploadFile(c *gin.Context) {
+	purpose := c.PostForm("purpose")
+	if purpose != batches.PurposeBatch {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request: purpose must be %q", batches.PurposeBatch),
+		})
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: file is required",
+		})
+		return
+	}
+	f, err := header.Open()
+	if err != nil {
+		h.fail(c, "failed to read upload", err)
+		return
+	}
+	defer f.Close()
+
+	file, err := h.store.CreateFile(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, header.Filename, purpose, f, h.maxFileBytes)
+	if err != nil {
+		h.fail(c, "failed to store file", err)
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+func (h *BatchHandler) HandleGetFile(c *gin.Context) {
+	file, err := h.store.GetFile(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to load file", err)
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+func (h *BatchHandler) HandleFileContent(c *gin.Context) {
+	file, content, err := h.store.OpenFile(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to open file", err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	c.DataFromReader(http.StatusOK, file.Bytes, "application/jsonl", content, nil)
+}
+
+func (h *BatchHandler) HandleCreate(c *gin.Context) {
+	var req createBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+	if err := validateBatchRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request: %s", err.Error()),
+		})
+		return
+	}
+
+	key := requestmeta.FromContext(c.Request.Context()).Key
+	batch, err := h.runner.Create(c.Request.Context(), key, req.InputFileID, req.Endpoint, req.CompletionWindow, req.Metadata)
+	if err != nil {
+		h.fail(c, "failed to create batch", err)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *BatchHandler) HandleList(c *gin.Context) {
+	limit := 20
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request: limit must be between 1 and 100",
+			})
+			return
+		}
+		limit = n
+	}
+
+	list, err := h.runner.List(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, limit)
+	if err != nil {
+		h.fail(c, "failed to list batches", err)
+		return
+	}
+	resp := gin.H{
+		"object":   "list",
+		"data":     list,
+		"has_more": len(list) == limit,
+	}
+	if len(list) > 0 {
+		resp["first_id"] = list[0].ID
+		resp["last_id"] = list[len(list)-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *BatchHandler) HandleGet(c *gin.Context) {
+	batch, err := h.runner.Get(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to load batch", err)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *BatchHandler) HandleCancel(c *gin.Context) {
+	batch, err := h.runner.Cancel(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to cancel batch", err)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *BatchHandler) fail(c *gin.Context, msg string, err error) {
+	switch {
+	case errors.Is(err, batches.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Not found",
+		})
+		return
+	case errors.Is(err, batches.ErrFileTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Invalid request: %s", err.Error()),
+		})
+		return
+	case errors.Is(err, batches.ErrInvalidInput), errors.Is(err, io.ErrUnexpectedEOF):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request: %s", err.Error()),
+		})
+		return
+	}
+	h.logger.ErrorContext(c.Request.Context(), msg, slog.String("id", c.Param("id")), slog.Any("error", err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to process batch request",
+	})
+}
+
+func validateBatchRequest(req *createBatchRequest) error {
+	if req.InputFileID == "" {
+		return fmt.Errorf("input_file_id is required")
+	}
+	if req.Endpoint != batches.EndpointChatCompletions {
+		return fmt.Errorf("endpoint must be %s", batches.EndpointChatCompletions)
+	}
+	if req.CompletionWindow != "24h" {
+		return fmt.Errorf("completion_window must be 24h")
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/llm-router/internal/jobs"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+type JobHandler struct {
+	runner *jobs.Runner
+	logger *slog.Logger
+}
+
+func NewJobHandler(runner *jobs.Runner, logger *slog.Logger) *JobHandler {
+	return &JobHandler{
+		runner: runner,
+		logger: logger,
+	}
+}
+
+type createJobRequest struct {
+	Request    *models.ChatCompletionRequest `json:"request"`
+	WebhookURL string                        `json:"webhook_url,omitempty"`
+	Metadata   map[string]string             `json:"metadata,omitempty"`
+}
+
+func (h *JobHandler) HandleCreate(c *gin.Context) {
+	var req createJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Request == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+	if err := validateJobRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request: %s", err.Error()),
+		})
+		return
+	}
+
+	// Jobs always produce a complete response.
+	req.Request.Stream = false
+	req.Request.StreamOptions = nil
+
+	meta := requestmeta.FromContext(c.Request.Context())
+	job := &jobs.Job{
+		ID:         jobs.NewID(),
+		Object:     "job",
+		Status:     jobs.StatusQueued,
+		Key:        meta.Key,
+		Route:      meta.Route,
+		Request:    req.Request,
+		WebhookURL: req.WebhookURL,
+		Metadata:   req.Metadata,
+		CreatedAt:  time.Now(),
+	}
+	if err := h.runner.Submit(c.Request.Context(), job); err != nil {
+		if errors.Is(err, jobs.ErrWebhookNotAllowed) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Invalid request: %s", err.Error()),
+			})
+			return
+		}
+		h.fail(c, "failed to submit job", err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (h *JobHandler) HandleGet(c *gin.Context) {
+	job, err := h.runner.Get(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to load job", err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *JobHandler) HandleCancel(c *gin.Context) {
+	job, err := h.runner.Cancel(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to cancel job", err)
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *JobHandler) fail(c *gin.Context, msg string, err error) {
+	if errors.Is(err, jobs.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Job not found",
+		})
+		return
+	}
+	h.logger.ErrorContext(c.Request.Context(), msg, slog.String("job_id", c.Param("id")), slog.Any("error", err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to process job request",
+	})
+}
+
+func validateJobRequest(req *createJobRequest) error {
+	if err := validateChatCompletionRequest(req.Request); err != nil {
+		return err
+	}
+	if req.WebhookURL != "" {
+		u, err := url.Parse(req.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook_url must be an http or https URL")
+		}
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,17 +15,20 @@ import (
 
 type LLMHandler struct {
 	llmService services.LLMService
+	logger     *slog.Logger
 }
 
-func NewLLMHandler(llmService services.LLMService) (*LLMHandler, error) {
+func NewLLMHandler(llmService services.LLMService, logger *slog.Logger) (*LLMHandler, error) {
 	return &LLMHandler{
 		llmService: llmService,
+		logger:     logger,
 	}, nil
 }
 
 func (h *LLMHandler) HandleChatCompletion(c *gin.Context) {
 	var req models.ChatCompletionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.DebugContext(c.Request.Context(), "invalid request body", slog.Any("error", err))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Invalid request format",
 		})
@@ -46,7 +51,18 @@ func (h *LLMHandler) HandleChatCompletion(c *gin.Context) {
 
 func (h *LLMHandler) handleNormalChatCompletion(c *gin.Context, req *models.ChatCompletionRequest) {
 	response, err := h.llmService.ChatCompletion(c.Request.Context(), req)
+	var reqErr *services.RequestError
+	if errors.As(err, &reqErr) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Invalid request: %s", reqErr.Error()),
+		})
+		return
+	}
 	if err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "chat completion failed",
+			slog.String("model", req.Model),
+			slog.Any("error", err),
+		)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to process chat completion",
 		})
@@ -56,15 +72,61 @@ func (h *LLMHandler) handleNormalChatCompletion(c *gin.Context, req *models.Chat
 }
 
 func (h *LLMHandler) handleStreamChatCompletion(c *gin.Context, req *models.ChatCompletionRequest) {
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-
 	streamCh, errCh := h.llmService.ChatCompletionStream(c.Request.Context(), req)
 
 	clientClosed := c.Writer.CloseNotify()
 
+	// Problems with the request itself are reported before the first chunk.
+	// Wait for that first event so they can be answered with a 400, as for
+	// non-streaming requests, before the event stream starts.
+	var first *models.ChatCompletionChunk
+	var firstErr error
+	ended := false
+peek:
+	for {
+		select {
+		case <-clientClosed:
+			return
+		case chunk, ok := <-streamCh:
+			first, ended = chunk, !ok
+			break peek
+		case err, ok := <-errCh:
+			if !ok {
+				errCh = nil
+				continue
+			}
+			if err == nil {
+				continue
+			}
+			var reqErr *services.RequestError
+			if errors.As(err, &reqErr) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error": fmt.Sprintf("Invalid request: %s", reqErr.Error()),
+				})
+				return
+			}
+			firstErr = err
+			break peek
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+
 	c.Stream(func(w io.Writer) bool {
+		switch {
+		case firstErr != nil:
+			return h.writeStreamError(c, req, firstErr)
+		case first != nil:
+			chunk := first
+			first = nil
+			return h.writeStreamChunk(c, chunk)
+		case ended:
+			c.SSEvent("data", "[DONE]")
+			return false
+		}
+
 		select {
 		case <-clientClosed:
 			return false
@@ -73,37 +135,52 @@ func (h *LLMHandler) handleStreamChatCompletion(c *gin.Context, req *models.Chat
 				c.SSEvent("data", "[DONE]")
 				return false
 			}
-
-			jsonData, err := json.Marshal(chunk)
-			if err != nil {
-				fmt.Printf("Error marshalling chunk to JSON: %v\n", err)
-				c.SSEvent("error", "Failed to process chat completion chunk")
-				return false
-			}
-			c.SSEvent("data", string(jsonData))
-			return true
+			return h.writeStreamChunk(c, chunk)
 
 		case err, ok := <-errCh:
 			if !ok {
-				return false
+				// The chunk channel decides when the stream is over.
+				errCh = nil
+				return true
 			}
 			if err != nil {
-				errorData, _ := json.Marshal(gin.H{"error": err.Error()})
-				c.SSEvent("error", string(errorData))
-				fmt.Printf("Error from stream service: %v\n", err)
-				return false
+				return h.writeStreamError(c, req, err)
 			}
 			return true
 		}
 	})
 }
 
+func (h *LLMHandler) writeStreamChunk(c *gin.Context, chunk *models.ChatCompletionChunk) bool {
+	jsonData, err := json.Marshal(chunk)
+	if err != nil {
+		h.logger.ErrorContext(c.Request.Context(), "failed to marshal chunk", slog.Any("error", err))
+		c.SSEvent("error", "Failed to process chat completion chunk")
+		return false
+	}
+	c.SSEvent("data", string(jsonData))
+	return true
+}
+
+func (h *LLMHandler) writeStreamError(c *gin.Context, req *models.ChatCompletionRequest, err error) bool {
+	errorData, _ := json.Marshal(gin.H{"error": err.Error()})
+	c.SSEvent("error", string(errorData))
+	h.logger.ErrorContext(c.Request.Context(), "chat completion stream failed",
+		slog.String("model", req.Model),
+		slog.Any("error", err),
+	)
+	return false
+}
+
 func validateChatCompletionRequest(req *models.ChatCompletionRequest) error {
 	if req.Model == "" {
 		return fmt.Errorf("model is required")
 	}
-	if len(req.Messages) == 0 {
+	if len(req.Messages) == 0 && req.Template == nil && req.SessionID == "" {
 		return fmt.Errorf("messages cannot be empty")
 	}
+	if req.Template != nil && req.Template.ID == "" {
+		return fmt.Errorf("template id is required")
+	}
 	return nil
 }
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/llm-router/internal/sessions"
+)
+
+type SessionHandler struct {
+	store  sessions.Store
+	logger *slog.Logger
+}
+
+func NewSessionHandler(store sessions.Store, logger *slog.Logger) *SessionHandler {
+	return &SessionHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+type createSessionRequest struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Messages []models.Message  `json:"messages,omitempty"`
+}
+
+type appendMessagesRequest struct {
+	Messages []models.Message `json:"messages"`
+}
+
+func (h *SessionHandler) HandleCreate(c *gin.Context) {
+	var req createSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil && c.Request.ContentLength != 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+
+	now := time.Now()
+	session := &sessions.Session{
+		ID:        sessions.NewID(),
+		Key:       requestmeta.FromContext(c.Request.Context()).Key,
+		Metadata:  req.Metadata,
+		Messages:  req.Messages,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if session.Messages == nil {
+		session.Messages = []models.Message{}
+	}
+	if err := h.store.Create(c.Request.Context(), session); err != nil {
+		h.fail(c, "failed to create session", err)
+		return
+	}
+	c.JSON(http.StatusCreated, session)
+}
+
+func (h *SessionHandler) HandleGet(c *gin.Context) {
+	session, err := h.store.Get(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to load session", err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *SessionHandler) HandleAppend(c *gin.Context) {
+	var req appendMessagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Messages) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: messages cannot be empty",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	key := requestmeta.FromContext(ctx).Key
+	if err := h.store.Append(ctx, key, c.Param("id"), req.Messages...); err != nil {
+		h.fail(c, "failed to append to session", err)
+		return
+	}
+	session, err := h.store.Get(ctx, key, c.Param("id"))
+	if err != nil {
+		h.fail(c, "failed to load session", err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+func (h *SessionHandler) HandleDelete(c *gin.Context) {
+	if err := h.store.Delete(c.Request.Context(), requestmeta.FromContext(c.Request.Context()).Key, c.Param("id")); err != nil {
+		h.fail(c, "failed to delete session", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SessionHandler) fail(c *gin.Context, msg string, err error) {
+	if errors.Is(err, sessions.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Session not found",
+		})
+		return
+	}
+	h.logger.ErrorContext(c.Request.Context(), msg, slog.String("session_id", c.Param("id")), slog.Any("error", err))
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": "Failed to process session request",
+	})
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/tokenizer"
+)
+
+// TokenizeRequest counts either plain text in Input or chat Messages, which
+// include the per-message framing a chat completion's prompt is billed for.
+type TokenizeRequest struct {
+	Model    string           `json:"model"`
+	Input    string           `json:"input,omitempty"`
+	Messages []models.Message `json:"messages,omitempty"`
+}
+
+type TokenizeResponse struct {
+	Model    string `json:"model"`
+	Encoding string `json:"encoding"`
+	// Exact is false for models whose tokenizer is approximated.
+	Exact  bool  `json:"exact"`
+	Tokens int64 `json:"tokens"`
+}
+
+func (h *LLMHandler) HandleTokenize(c *gin.Context) {
+	var req TokenizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request format",
+		})
+		return
+	}
+	if req.Model == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request: model is required",
+		})
+		return
+	}
+
+	tok := tokenizer.ForModel(req.Model)
+	resp := TokenizeResponse{
+		Model:    req.Model,
+		Encoding: tok.Encoding,
+		Exact:    tok.Exact,
+	}
+	if len(req.Messages) > 0 {
+		resp.Tokens = tok.CountMessages(req.Messages)
+	} else {
+		resp.Tokens = tok.Count(req.Input)
+	}
+	c.JSON(http.StatusOK, resp)
+}
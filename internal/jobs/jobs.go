@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+var ErrNotFound = errors.New("job not found")
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+func (s Status) Done() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Job is a chat completion run in the background. Key and Route are those
+// of the request that submitted it, so the completion is handled under the
+// same policies as a direct call.
+type Job struct {
+	ID         string                         `json:"id"`
+	Object     string                         `json:"object"`
+	Status     Status                         `json:"status"`
+	Key        string                         `json:"-"`
+	Route      string                         `json:"-"`
+	Request    *models.ChatCompletionRequest  `json:"request"`
+	Response   *models.ChatCompletionResponse `json:"response,omitempty"`
+	Error      string                         `json:"error,omitempty"`
+	WebhookURL string                         `json:"webhook_url,omitempty"`
+	Metadata   map[string]string              `json:"metadata,omitempty"`
+	CreatedAt  time.Time                      `json:"created_at"`
+	StartedAt  *time.Time                     `json:"started_at,omitempty"`
+	FinishedAt *time.Time                     `json:"finished_at,omitempty"`
+}
+
+func NewID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "job_" + hex.EncodeToString(b)
+}
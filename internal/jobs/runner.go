@@ -0,0 +1,317 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// pollInterval bounds how long a queued job can wait when no worker was
+// woken for it, e.g. one left over from before a restart.
+const pollInterval = 5 * time.Second
+
+// webhookAttempts is how many times a webhook is tried before giving up.
+const webhookAttempts = 3
+
+// CompleteFunc runs a job's chat completion.
+type CompleteFunc func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error)
+
+type Options struct {
+	Workers  int
+	Timeout  time.Duration
+	Webhooks WebhookOptions
+}
+
+// Runner executes queued jobs on a fixed number of workers. Jobs left
+// running when the router stopped are queued again on start, and jobs
+// interrupted by Close are put back in the queue.
+type Runner struct {
+	store    *Store
+	complete CompleteFunc
+	opts     Options
+	logger   *slog.Logger
+	webhooks *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wake   chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]*runningJob
+}
+
+type runningJob struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	canceled bool
+}
+
+func NewRunner(store *Store, complete CompleteFunc, opts Options, logger *slog.Logger) (*Runner, error) {
+	requeued, err := store.Requeue(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue interrupted jobs: %w", err)
+	}
+	if requeued > 0 {
+		logger.Info("requeued interrupted jobs", slog.Int64("jobs", requeued))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Runner{
+		store:    store,
+		complete: complete,
+		opts:     opts,
+		logger:   logger,
+		webhooks: newWebhookClient(opts.Webhooks),
+		ctx:      ctx,
+		cancel:   cancel,
+		wake:     make(chan struct{}, 1),
+		running:  make(map[string]*runningJob),
+	}
+	for i := 0; i < max(opts.Workers, 1); i++ {
+		r.wg.Add(1)
+		go r.work()
+	}
+	return r, nil
+}
+
+func (r *Runner) Submit(ctx context.Context, job *Job) error {
+	if job.WebhookURL != "" {
+		if err := r.opts.Webhooks.CheckWebhookURL(job.WebhookURL); err != nil {
+			return err
+		}
+	}
+	if err := r.store.Insert(ctx, job); err != nil {
+		return err
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (r *Runner) Get(ctx context.Context, key, id string) (*Job, error) {
+	return r.store.Get(ctx, key, id)
+}
+
+// Cancel stops a queued or running job. Finished jobs are returned as they
+// are.
+func (r *Runner) Cancel(ctx context.Context, key, id string) (*Job, error) {
+	job, err := r.store.Get(ctx, key, id)
+	if err != nil {
+		return nil, err
+	}
+
+	switch job.Status {
+	case StatusQueued:
+		now := time.Now()
+		job.Status = StatusCanceled
+		job.FinishedAt = &now
+		ok, err := r.store.Update(ctx, job, StatusQueued)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			r.notifyAsync(context.WithoutCancel(ctx), job)
+			return job, nil
+		}
+		// A worker claimed it meanwhile; cancel it as a running job.
+		fallthrough
+	case StatusRunning:
+		r.mu.Lock()
+		if running, ok := r.running[id]; ok {
+			running.canceled = true
+			running.cancel()
+		}
+		r.mu.Unlock()
+	}
+	return r.store.Get(ctx, key, id)
+}
+
+// Close stops the workers, returning interrupted jobs to the queue.
+func (r *Runner) Close() error {
+	r.cancel()
+	r.wg.Wait()
+	return r.store.Close()
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, running, err := r.claim()
+		if err != nil && r.ctx.Err() == nil {
+			r.logger.Error("failed to claim job", slog.Any("error", err))
+		}
+		if job != nil {
+			r.run(job, running)
+			continue
+		}
+
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claim takes the oldest queued job and registers it as running in the same
+// critical section, so Cancel never sees a claimed job it cannot stop.
+func (r *Runner) claim() (*Job, *runningJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	job, err := r.store.Claim(r.ctx)
+	if job == nil {
+		return nil, nil, err
+	}
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if r.opts.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(r.ctx, r.opts.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(r.ctx)
+	}
+	running := &runningJob{ctx: ctx, cancel: cancel}
+	r.running[job.ID] = running
+	return job, running, nil
+}
+
+func (r *Runner) run(job *Job, running *runningJob) {
+	defer running.cancel()
+	defer func() {
+		r.mu.Lock()
+		delete(r.running, job.ID)
+		r.mu.Unlock()
+	}()
+
+	ctx := requestmeta.WithMeta(running.ctx, requestmeta.Meta{
+		RequestID: job.ID,
+		Route:     job.Route,
+		Key:       job.Key,
+	})
+	r.logger.InfoContext(ctx, "job started", slog.String("model", job.Request.Model))
+
+	resp, err := r.complete(ctx, job.Request)
+
+	r.mu.Lock()
+	userCanceled := running.canceled
+	r.mu.Unlock()
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		// A finished completion is kept even if it was cancelled meanwhile.
+		job.Status = StatusSucceeded
+		job.Response = resp
+	case userCanceled:
+		job.Status = StatusCanceled
+	case r.ctx.Err() != nil:
+		// The router is shutting down; leave the job for the next start.
+		job.Status = StatusQueued
+		job.StartedAt = nil
+		now = time.Time{}
+	default:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			job.Error = "job timed out"
+		}
+	}
+	if !now.IsZero() {
+		job.FinishedAt = &now
+	}
+
+	if _, err := r.store.Update(context.Background(), job, StatusRunning); err != nil {
+		r.logger.ErrorContext(ctx, "failed to save job", slog.Any("error", err))
+		return
+	}
+	r.logger.InfoContext(ctx, "job finished", slog.String("status", string(job.Status)))
+
+	if job.Status.Done() {
+		r.notifyAsync(context.WithoutCancel(ctx), job)
+	}
+}
+
+func (r *Runner) notifyAsync(ctx context.Context, job *Job) {
+	if job.WebhookURL == "" {
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.notify(ctx, job)
+	}()
+}
+
+// notify POSTs the finished job to its webhook, retrying with backoff. It
+// gives up without waiting out the backoff once the runner is closed.
+func (r *Runner) notify(ctx context.Context, job *Job) {
+	body, err := json.Marshal(job)
+	if err != nil {
+		r.logger.ErrorContext(ctx, "failed to encode job for webhook", slog.Any("error", err))
+		return
+	}
+
+	backoff := time.Second
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = r.post(ctx, job.WebhookURL, body)
+		if err == nil {
+			return
+		}
+		if attempt < webhookAttempts {
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-r.ctx.Done():
+				timer.Stop()
+				r.logger.WarnContext(ctx, "job webhook abandoned on shutdown",
+					slog.String("url", job.WebhookURL),
+					slog.Any("error", err),
+				)
+				return
+			}
+			backoff *= 2
+		}
+	}
+	r.logger.WarnContext(ctx, "job webhook failed",
+		slog.String("url", job.WebhookURL),
+		slog.Any("error", err),
+	)
+}
+
+func (r *Runner) post(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.opts.Webhooks.Secret != "" {
+		signWebhook(req, r.opts.Webhooks.Secret, body, time.Now())
+	}
+
+	resp, err := r.webhooks.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
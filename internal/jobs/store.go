@@ -0,0 +1,180 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const createJobsTable = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id          TEXT PRIMARY KEY,
+	key         TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	created_at  TEXT NOT NULL,
+	job         TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS jobs_status_created_at ON jobs (status, created_at);
+`
+
+// createdAtLayout is RFC 3339 with a fixed nine fractional digits, so that
+// created_at sorts as text in time order; RFC3339Nano trims trailing zeros.
+const createdAtLayout = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Store is a persistent job queue in a local SQLite database. Each row keeps
+// the whole job as JSON next to the columns used to pick the next one.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job database: %w", err)
+	}
+	if _, err := db.Exec(createJobsTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create jobs table: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Insert(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(storedJob{Job: job, Key: job.Key, Route: job.Route})
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx,
+		`INSERT INTO jobs (id, key, status, created_at, job) VALUES (?, ?, ?, ?, ?)`,
+		job.ID, job.Key, job.Status, job.CreatedAt.UTC().Format(createdAtLayout), string(data),
+	)
+	return err
+}
+
+// Update saves job, but only while it is still in the from status, so a job
+// canceled meanwhile is not overwritten. It reports whether it saved.
+func (s *Store) Update(ctx context.Context, job *Job, from Status) (bool, error) {
+	data, err := json.Marshal(storedJob{Job: job, Key: job.Key, Route: job.Route})
+	if err != nil {
+		return false, err
+	}
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE jobs SET status = ?, job = ? WHERE id = ? AND status = ?`,
+		job.Status, string(data), job.ID, from,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Get returns the job with id owned by key.
+func (s *Store) Get(ctx context.Context, key, id string) (*Job, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT job FROM jobs WHERE id = ? AND key = ?`, id, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeJob(data)
+}
+
+// Claim marks the oldest queued job as running and returns it, or nil when
+// the queue is empty. Workers race for the same job; the update only
+// succeeds for the one that still finds it queued.
+func (s *Store) Claim(ctx context.Context) (*Job, error) {
+	for {
+		var data string
+		err := s.db.QueryRowContext(ctx,
+			`SELECT job FROM jobs WHERE status = ? ORDER BY created_at LIMIT 1`, StatusQueued,
+		).Scan(&data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		job, err := decodeJob(data)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		job.Status = StatusRunning
+		job.StartedAt = &now
+		claimed, err := s.Update(ctx, job, StatusQueued)
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return job, nil
+		}
+	}
+}
+
+// Requeue puts jobs left running by a previous process back in the queue.
+func (s *Store) Requeue(ctx context.Context) (int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT job FROM jobs WHERE status = ?`, StatusRunning)
+	if err != nil {
+		return 0, err
+	}
+	var running []*Job
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		job, err := decodeJob(data)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		running = append(running, job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var n int64
+	for _, job := range running {
+		job.Status = StatusQueued
+		job.StartedAt = nil
+		if ok, err := s.Update(ctx, job, StatusRunning); err != nil {
+			return n, err
+		} else if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// storedJob adds the fields Job keeps out of its API representation.
+type storedJob struct {
+	*Job
+	Key   string `json:"key"`
+	Route string `json:"route"`
+}
+
+func decodeJob(data string) (*Job, error) {
+	stored := storedJob{Job: &Job{}}
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	stored.Job.Key = stored.Key
+	stored.Job.Route = stored.Route
+	return stored.Job, nil
+}
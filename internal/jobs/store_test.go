@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClaimOldestFirst(t *testing.T) {
+	store, err := NewStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// In RFC3339Nano the later job would format as "...00.5Z" and sort
+	// before the earlier "...00Z".
+	first := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	for _, job := range []*Job{
+		{ID: "job_later", Status: StatusQueued, CreatedAt: first.Add(500 * time.Millisecond)},
+		{ID: "job_first", Status: StatusQueued, CreatedAt: first},
+	} {
+		if err := store.Insert(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, want := range []string{"job_first", "job_later"} {
+		job, err := store.Claim(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job == nil || job.ID != want {
+			t.Fatalf("claimed %v, want %s", job, want)
+		}
+	}
+}
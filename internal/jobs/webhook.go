@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Webhook request headers. The signature is the hex HMAC-SHA256, keyed by
+// the webhook secret, of the timestamp, a dot and the body.
+const (
+	WebhookTimestampHeader = "X-Router-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Router-Webhook-Signature"
+)
+
+var ErrWebhookNotAllowed = errors.New("webhook_url is not allowed")
+
+// WebhookOptions controls where job webhooks are sent and how they are
+// signed.
+type WebhookOptions struct {
+	// AllowedHosts, when set, are the only hosts webhooks are sent to.
+	// Otherwise any host is allowed except those at loopback, private,
+	// link-local or other non-public addresses.
+	AllowedHosts []string
+	// Secret signs every webhook when set.
+	Secret string
+}
+
+// CheckWebhookURL reports whether webhooks may be sent to raw. Host names
+// are resolved when the webhook is sent, so they are only checked against
+// the allowlist here.
+func (o WebhookOptions) CheckWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: must be an http or https URL", ErrWebhookNotAllowed)
+	}
+	host := strings.ToLower(u.Hostname())
+	if len(o.AllowedHosts) > 0 {
+		if !slices.Contains(o.AllowedHosts, host) {
+			return fmt.Errorf("%w: host %s is not in the allowlist", ErrWebhookNotAllowed, host)
+		}
+		return nil
+	}
+	if ip := net.ParseIP(host); (ip != nil && !publicIP(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: host %s is not public", ErrWebhookNotAllowed, host)
+	}
+	return nil
+}
+
+// newWebhookClient returns the client webhooks are sent with. Without an
+// allowlist it refuses to connect to non-public addresses, whatever a host
+// name resolves to. Redirects are not followed, since they could lead
+// anywhere.
+func newWebhookClient(opts WebhookOptions) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if len(opts.AllowedHosts) == 0 {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return fmt.Errorf("%w: address %s is not public", ErrWebhookNotAllowed, host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast()
+}
+
+// signWebhook sets the signature headers of a webhook request.
+func signWebhook(req *http.Request, secret string, body []byte, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+}
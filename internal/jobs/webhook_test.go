@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckWebhookURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    WebhookOptions
+		url     string
+		allowed bool
+	}{
+		{"public host", WebhookOptions{}, "https://hooks.example.com/job", true},
+		{"public address", WebhookOptions{}, "http://93.184.216.34/job", true},
+		{"not http", WebhookOptions{}, "ftp://hooks.example.com/job", false},
+		{"no host", WebhookOptions{}, "https:///job", false},
+		{"localhost", WebhookOptions{}, "http://localhost:8080/job", false},
+		{"loopback", WebhookOptions{}, "http://127.0.0.1/job", false},
+		{"loopback v6", WebhookOptions{}, "http://[::1]/job", false},
+		{"private", WebhookOptions{}, "http://10.0.0.5/job", false},
+		{"link-local metadata", WebhookOptions{}, "http://169.254.169.254/latest", false},
+		{"unspecified", WebhookOptions{}, "http://0.0.0.0/job", false},
+		{"allowlisted", WebhookOptions{AllowedHosts: []string{"hooks.internal"}}, "http://HOOKS.internal:9000/job", true},
+		{"allowlisted private", WebhookOptions{AllowedHosts: []string{"10.0.0.5"}}, "http://10.0.0.5/job", true},
+		{"not allowlisted", WebhookOptions{AllowedHosts: []string{"hooks.internal"}}, "https://hooks.example.com/job", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.CheckWebhookURL(tt.url)
+			if tt.allowed && err != nil {
+				t.Fatalf("CheckWebhookURL(%q) = %v, want allowed", tt.url, err)
+			}
+			if !tt.allowed && !errors.Is(err, ErrWebhookNotAllowed) {
+				t.Fatalf("CheckWebhookURL(%q) = %v, want ErrWebhookNotAllowed", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestWebhookClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL, nil)
+	if _, err := newWebhookClient(WebhookOptions{}).Do(req); !errors.Is(err, ErrWebhookNotAllowed) {
+		t.Fatalf("webhook to %s: err = %v, want ErrWebhookNotAllowed", srv.URL, err)
+	}
+
+	req, _ = http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL, nil)
+	resp, err := newWebhookClient(WebhookOptions{AllowedHosts: []string{"127.0.0.1"}}).Do(req)
+	if err != nil {
+		t.Fatalf("allowlisted webhook to %s: %v", srv.URL, err)
+	}
+	resp.Body.Close()
+}
+
+func TestSignWebhook(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://hooks.example.com", bytes.NewReader(nil))
+	signWebhook(req, "secret", []byte(`{"id":"job_1"}`), time.Unix(1700000000, 0))
+
+	if got := req.Header.Get(WebhookTimestampHeader); got != "1700000000" {
+		t.Errorf("timestamp = %q", got)
+	}
+	// printf '1700000000.{"id":"job_1"}' | openssl dgst -sha256 -hmac secret
+	const want = "sha256=287e6e830f67761f411bf6f58d64f314188daeda980343d77443a9e6f4b74738"
+	if got := req.Header.Get(WebhookSignatureHeader); got != want {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestCloseStopsWebhookBackoff(t *testing.T) {
+	posted := make(chan struct{}, webhookAttempts)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted <- struct{}{}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	store, err := NewStore(filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Webhooks: WebhookOptions{AllowedHosts: []string{"127.0.0.1"}}}
+	r, err := NewRunner(store, nil, opts, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.notifyAsync(context.Background(), &Job{ID: "job_1", WebhookURL: srv.URL})
+	<-posted
+
+	start := time.Now()
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("Close took %v waiting out the webhook backoff", elapsed)
+	}
+}
@@ -0,0 +1,103 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/llm-router/internal/requestmeta"
+)
+
+const redacted = "[REDACTED]"
+
+var sensitiveKeys = map[string]bool{
+	"api_key":       true,
+	"apikey":        true,
+	"authorization": true,
+	"x-api-key":     true,
+	"password":      true,
+	"secret":        true,
+	"token":         true,
+}
+
+// secretPattern matches provider API keys and bearer credentials that may
+// slip into error messages or free-form values.
+var secretPattern = regexp.MustCompile(`(?i)(sk-(?:ant-)?[a-z0-9_\-]{8,}|bearer\s+[a-z0-9._\-]{8,})`)
+
+// New builds the process logger. level is one of debug, info, warn or error;
+// format is "json" or "text".
+func New(w io.Writer, level, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level:       ParseLevel(level),
+		ReplaceAttr: redact,
+	}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		handler = slog.NewJSONHandler(w, opts)
+	}
+	return slog.New(&contextHandler{Handler: handler})
+}
+
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Scrub(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Scrub(err.Error()))
+		}
+	}
+	return a
+}
+
+// Scrub masks anything that looks like a credential in s.
+func Scrub(s string) string {
+	return secretPattern.ReplaceAllString(s, redacted)
+}
+
+// contextHandler adds the request ID, route and key fingerprint carried by
+// the context to every record logged with a *Context method.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if meta, ok := requestmeta.Lookup(ctx); ok {
+		r.AddAttrs(
+			slog.String("request_id", meta.RequestID),
+			slog.String("route", meta.Route),
+			slog.String("key", meta.Key),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/llm-router/internal/accounting"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "llm_router"
+
+var labels = []string{"provider", "model", "route", "key"}
+
+// CircuitState values exported on the circuit breaker gauge.
+type CircuitState float64
+
+const (
+	CircuitClosed   CircuitState = 0
+	CircuitHalfOpen CircuitState = 1
+	CircuitOpen     CircuitState = 2
+)
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests     *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	ttft         *prometheus.HistogramVec
+	interToken   *prometheus.HistogramVec
+	tokens       *prometheus.CounterVec
+	cost         *prometheus.CounterVec
+	inFlight     *prometheus.GaugeVec
+	circuitState *prometheus.GaugeVec
+	hedges       *prometheus.CounterVec
+	expRequests  *prometheus.CounterVec
+	expLatency   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Upstream chat completion requests.",
+		}, append(labels, "stream")),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Failed upstream chat completion requests by error class.",
+		}, append(labels, "class")),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "Time from sending the upstream request to the last byte of the response.",
+			Buckets:   []float64{.1, .25, .5, 1, 2, 4, 8, 16, 32, 64, 128},
+		}, append(labels, "stream")),
+		ttft: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "time_to_first_token_seconds",
+			Help:      "Time from sending a streaming request to its first content chunk.",
+			Buckets:   []float64{.05, .1, .25, .5, .75, 1, 1.5, 2, 4, 8, 16},
+		}, labels),
+		interToken: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "inter_token_latency_seconds",
+			Help:      "Time between consecutive content chunks of a stream.",
+			Buckets:   []float64{.005, .01, .02, .04, .08, .16, .32, .64, 1.28},
+		}, labels),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tokens_total",
+			Help:      "Tokens consumed by direction: in (prompt, including cached), out (completion), cache_read and cache_write.",
+		}, append(labels, "direction")),
+		cost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cost_usd_total",
+			Help:      "Estimated spend in USD at list prices.",
+		}, labels),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "in_flight_requests",
+			Help:      "Upstream requests currently in progress.",
+		}, labels),
+		circuitState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_state",
+			Help:      "Circuit breaker state per upstream member (0 closed, 1 half-open, 2 open).",
+		}, []string{"provider", "member"}),
+		hedges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "hedged_requests_total",
+			Help:      "Requests eligible for hedging by outcome: not_needed, primary_won, backup_won or failed.",
+		}, []string{"route", "outcome"}),
+		expRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "experiment_requests_total",
+			Help:      "Requests assigned to an experiment arm by outcome: ok or error.",
+		}, []string{"experiment", "arm", "outcome"}),
+		expLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "experiment_request_duration_seconds",
+			Help:      "End-to-end duration of requests assigned to an experiment arm.",
+			Buckets:   []float64{.1, .25, .5, 1, 2, 4, 8, 16, 32, 64, 128},
+		}, []string{"experiment", "arm"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.errors,
+		m.latency,
+		m.ttft,
+		m.interToken,
+		m.tokens,
+		m.cost,
+		m.inFlight,
+		m.circuitState,
+		m.hedges,
+		m.expRequests,
+		m.expLatency,
+	)
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Record implements accounting.Recorder so token and cost counters are fed
+// from the same records as the usage ledger.
+func (m *Metrics) Record(ctx context.Context, rec accounting.Record) {
+	l := labelValues(ctx, rec.Provider, rec.Model)
+	m.tokens.WithLabelValues(append(l, "in")...).Add(float64(rec.Usage.PromptTokens))
+	m.tokens.WithLabelValues(append(l, "out")...).Add(float64(rec.Usage.CompletionTokens))
+	m.tokens.WithLabelValues(append(l, "cache_read")...).Add(float64(rec.Usage.CacheReadTokens))
+	m.tokens.WithLabelValues(append(l, "cache_write")...).Add(float64(rec.Usage.CacheWriteTokens))
+	m.cost.WithLabelValues(l...).Add(rec.Cost)
+}
+
+func (m *Metrics) SetCircuitState(provider, member string, state CircuitState) {
+	m.circuitState.WithLabelValues(provider, member).Set(float64(state))
+}
+
+func (m *Metrics) RecordHedge(ctx context.Context, outcome string) {
+	m.hedges.WithLabelValues(requestmeta.FromContext(ctx).Route, outcome).Inc()
+}
+
+func (m *Metrics) RecordExperiment(ctx context.Context, experiment, arm string, latency time.Duration, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	m.expRequests.WithLabelValues(experiment, arm, outcome).Inc()
+	m.expLatency.WithLabelValues(experiment, arm).Observe(latency.Seconds())
+}
+
+func labelValues(ctx context.Context, provider, model string) []string {
+	meta := requestmeta.FromContext(ctx)
+	return []string{provider, model, meta.Route, meta.Key}
+}
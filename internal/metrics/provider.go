@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+)
+
+type instrumentedProvider struct {
+	providers.Provider
+	metrics *Metrics
+}
+
+// InstrumentProvider wraps p so every call is counted and timed.
+func (m *Metrics) InstrumentProvider(p providers.Provider) providers.Provider {
+	return &instrumentedProvider{Provider: p, metrics: m}
+}
+
+func (p *instrumentedProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	l := labelValues(ctx, p.Name(), req.Model)
+	p.metrics.requests.WithLabelValues(append(l, "false")...).Inc()
+	inFlight := p.metrics.inFlight.WithLabelValues(l...)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	resp, err := p.Provider.ChatCompletion(ctx, req)
+	p.metrics.latency.WithLabelValues(append(l, "false")...).Observe(time.Since(start).Seconds())
+	if err != nil {
+		p.metrics.errors.WithLabelValues(append(l, providers.ErrorClass(err))...).Inc()
+	}
+	return resp, err
+}
+
+func (p *instrumentedProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	l := labelValues(ctx, p.Name(), req.Model)
+	p.metrics.requests.WithLabelValues(append(l, "true")...).Inc()
+	inFlight := p.metrics.inFlight.WithLabelValues(l...)
+	inFlight.Inc()
+
+	start := time.Now()
+	chunkCh, errCh := p.Provider.ChatCompletionStream(ctx, req)
+
+	var last time.Time
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			if hasContent(chunk) {
+				now := time.Now()
+				if last.IsZero() {
+					p.metrics.ttft.WithLabelValues(l...).Observe(now.Sub(start).Seconds())
+				} else {
+					p.metrics.interToken.WithLabelValues(l...).Observe(now.Sub(last).Seconds())
+				}
+				last = now
+			}
+			return chunk
+		},
+		OnError: func(err error) {
+			p.metrics.errors.WithLabelValues(append(l, providers.ErrorClass(err))...).Inc()
+		},
+		OnDone: func() {
+			p.metrics.latency.WithLabelValues(append(l, "true")...).Observe(time.Since(start).Seconds())
+			inFlight.Dec()
+		},
+	})
+}
+
+func hasContent(chunk *models.ChatCompletionChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"sort"
+	"strings"
+)
+
+// ChunkAccumulator reassembles a streamed completion into the response the
+// same request would have produced without streaming.
+type ChunkAccumulator struct {
+	id      string
+	model   string
+	created int64
+	usage   *Usage
+	choices map[int]*accumulatedChoice
+}
+
+type accumulatedChoice struct {
+	role         string
+	content      strings.Builder
+	finishReason string
+}
+
+func NewChunkAccumulator() *ChunkAccumulator {
+	return &ChunkAccumulator{choices: make(map[int]*accumulatedChoice)}
+}
+
+func (a *ChunkAccumulator) Add(chunk *ChatCompletionChunk) {
+	if a.id == "" {
+		a.id = chunk.ID
+		a.created = chunk.Created
+	}
+	if chunk.Model != "" {
+		a.model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		a.usage = chunk.Usage
+	}
+
+	for _, c := range chunk.Choices {
+		choice, ok := a.choices[c.Index]
+		if !ok {
+			choice = &accumulatedChoice{}
+			a.choices[c.Index] = choice
+		}
+		if c.Delta.Role != "" {
+			choice.role = c.Delta.Role
+		}
+		choice.content.WriteString(c.Delta.Content)
+		if c.FinishReason != nil {
+			choice.finishReason = *c.FinishReason
+		}
+	}
+}
+
+// Usage returns the usage reported by the stream, or nil if none was seen.
+func (a *ChunkAccumulator) Usage() *Usage {
+	return a.usage
+}
+
+// Content returns the concatenated text of all choices.
+func (a *ChunkAccumulator) Content() string {
+	var b strings.Builder
+	for _, choice := range a.sortedChoices() {
+		b.WriteString(choice.Message.Content)
+	}
+	return b.String()
+}
+
+func (a *ChunkAccumulator) Response() *ChatCompletionResponse {
+	resp := &ChatCompletionResponse{
+		ID:      a.id,
+		Object:  "chat.completion",
+		Created: a.created,
+		Model:   a.model,
+		Choices: a.sortedChoices(),
+	}
+	if a.usage != nil {
+		resp.Usage = *a.usage
+	}
+	return resp
+}
+
+func (a *ChunkAccumulator) sortedChoices() []ChatCompletionChoice {
+	choices := make([]ChatCompletionChoice, 0, len(a.choices))
+	for index, c := range a.choices {
+		role := c.role
+		if role == "" {
+			role = "assistant"
+		}
+		choices = append(choices, ChatCompletionChoice{
+			Index:        index,
+			Message:      ChatMessage{Role: role, Content: c.content.String()},
+			FinishReason: c.finishReason,
+		})
+	}
+	sort.Slice(choices, func(i, j int) bool { return choices[i].Index < choices[j].Index })
+	return choices
+}
+
+// ChunksFromResponse replays a complete response as a stream: a role chunk,
+// the content split on word boundaries, a finish chunk and, when
+// includeUsage is set, a usage-only chunk.
+func ChunksFromResponse(resp *ChatCompletionResponse, includeUsage bool) []*ChatCompletionChunk {
+	newChunk := func(choices ...ChatCompletionChunkChoice) *ChatCompletionChunk {
+		return &ChatCompletionChunk{
+			ID:      resp.ID,
+			Object:  "chat.completion.chunk",
+			Created: resp.Created,
+			Model:   resp.Model,
+			Choices: choices,
+		}
+	}
+
+	var chunks []*ChatCompletionChunk
+	for _, choice := range resp.Choices {
+		chunks = append(chunks, newChunk(ChatCompletionChunkChoice{
+			Index: choice.Index,
+			Delta: ChatMessage{Role: choice.Message.Role},
+		}))
+		for _, piece := range splitWords(choice.Message.Content) {
+			chunks = append(chunks, newChunk(ChatCompletionChunkChoice{
+				Index: choice.Index,
+				Delta: ChatMessage{Content: piece},
+			}))
+		}
+		reason := choice.FinishReason
+		chunks = append(chunks, newChunk(ChatCompletionChunkChoice{
+			Index:        choice.Index,
+			FinishReason: &reason,
+		}))
+	}
+
+	if includeUsage {
+		usage := resp.Usage
+		last := newChunk()
+		last.Choices = []ChatCompletionChunkChoice{}
+		last.Usage = &usage
+		chunks = append(chunks, last)
+	}
+	return chunks
+}
+
+// splitWords cuts s after each run of whitespace so the pieces concatenate
+// back to s exactly.
+func splitWords(s string) []string {
+	var pieces []string
+	start := 0
+	for i := 1; i < len(s); i++ {
+		if isSpace(s[i-1]) && !isSpace(s[i]) {
+			pieces = append(pieces, s[start:i])
+			start = i
+		}
+	}
+	if start < len(s) {
+		pieces = append(pieces, s[start:])
+	}
+	return pieces
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -1,18 +1,61 @@
 package models
 
 type ChatCompletionRequest struct {
-	Model       string          `json:"model"`
-	Messages    []openAIMessage `json:"messages"`
-	MaxTokens   *int64          `json:"max_tokens,omitempty"`
-	Temperature *float64        `json:"temperature,omitempty"`
-	TopP        *float64        `json:"top_p,omitempty"`
-	Stop        []string        `json:"stop,omitempty"`
-	Stream      bool            `json:"stream,omitempty"`
-	User        string          `json:"user,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	MaxTokens     *int64         `json:"max_tokens,omitempty"`
+	Temperature   *float64       `json:"temperature,omitempty"`
+	TopP          *float64       `json:"top_p,omitempty"`
+	Stop          []string       `json:"stop,omitempty"`
+	Stream        bool           `json:"stream,omitempty"`
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+	User          string         `json:"user,omitempty"`
+	// Template asks the router to build the prompt from a stored template.
+	// Its messages come before any sent in Messages.
+	Template *TemplateRef `json:"template,omitempty"`
+	// SessionID continues a stored conversation: its history is sent after
+	// the template's messages and before Messages, and Messages plus the
+	// reply are added to it.
+	SessionID string `json:"session_id,omitempty"`
 }
 
-type openAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-	Name    string `json:"name,omitempty"`
+type Message struct {
+	Role         string        `json:"role"`
+	Content      string        `json:"content"`
+	Name         string        `json:"name,omitempty"`
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+}
+
+// CacheControl marks a message as a prompt caching breakpoint. Providers
+// without explicit breakpoints ignore it.
+type CacheControl struct {
+	Type string `json:"type"`
+}
+
+// TemplateRef selects a stored prompt template. A zero Version means the
+// latest one.
+type TemplateRef struct {
+	ID        string            `json:"id"`
+	Version   int               `json:"version,omitempty"`
+	Variables map[string]string `json:"variables,omitempty"`
+	// Rendered is set by the router to the number of messages at the front
+	// of the request that the template produced.
+	Rendered int `json:"-"`
+}
+
+// RenderedMessages returns how many of the request's leading messages came
+// from its template.
+func (r *ChatCompletionRequest) RenderedMessages() int {
+	if r.Template == nil {
+		return 0
+	}
+	return min(r.Template.Rendered, len(r.Messages))
+}
+
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage,omitempty"`
+}
+
+func (r *ChatCompletionRequest) IncludeUsage() bool {
+	return r.StreamOptions != nil && r.StreamOptions.IncludeUsage
 }
@@ -5,6 +5,10 @@ type ChatMessage struct {
 	Content string `json:"content"`
 }
 
+// FinishReasonContentFilter marks a choice that was cut short or withheld
+// because a content filter triggered.
+const FinishReasonContentFilter = "content_filter"
+
 type ChatCompletionChoice struct {
 	Index        int         `json:"index"`
 	Message      ChatMessage `json:"message"`
@@ -17,10 +21,20 @@ type ChatCompletionChunkChoice struct {
 	FinishReason *string     `json:"finish_reason,omitempty"`
 }
 
+// Usage follows OpenAI's shape: PromptTokens includes any prompt tokens read
+// from or written to the provider's prompt cache, which are also broken out
+// below.
 type Usage struct {
-	PromptTokens     int64 `json:"prompt_tokens"`     // int -> int64
-	CompletionTokens int64 `json:"completion_tokens"` // int -> int64
-	TotalTokens      int64 `json:"total_tokens"`      // int -> int64
+	PromptTokens        int64                `json:"prompt_tokens"`     // int -> int64
+	CompletionTokens    int64                `json:"completion_tokens"` // int -> int64
+	TotalTokens         int64                `json:"total_tokens"`      // int -> int64
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+	CacheReadTokens     int64                `json:"cache_read_input_tokens,omitempty"`
+	CacheWriteTokens    int64                `json:"cache_creation_input_tokens,omitempty"`
+}
+
+type PromptTokensDetails struct {
+	CachedTokens int64 `json:"cached_tokens"`
 }
 
 type ChatCompletionResponse struct {
@@ -30,17 +44,38 @@ type ChatCompletionResponse struct {
 	Model   string                 `json:"model"`
 	Choices []ChatCompletionChoice `json:"choices"`
 	Usage   Usage                  `json:"usage"`
-	Error   *struct {
+	// Ensemble lists the candidates behind an ensemble model's answer when
+	// the ensemble is configured to include them.
+	Ensemble *Ensemble `json:"ensemble,omitempty"`
+	Error    *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
 		Code    string `json:"code"`
 	} `json:"error,omitempty"`
 }
 
+// Ensemble describes how an ensemble answer was chosen. Chosen indexes
+// Candidates; Strategy is "judge" or "consensus".
+type Ensemble struct {
+	Strategy   string              `json:"strategy"`
+	Chosen     int                 `json:"chosen"`
+	Candidates []EnsembleCandidate `json:"candidates"`
+}
+
+type EnsembleCandidate struct {
+	Model        string `json:"model"`
+	Content      string `json:"content,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+	Error        string `json:"error,omitempty"`
+	Usage        *Usage `json:"usage,omitempty"`
+	LatencyMS    int64  `json:"latency_ms"`
+}
+
 type ChatCompletionChunk struct {
 	ID      string                      `json:"id"`
 	Object  string                      `json:"object"`
 	Created int64                       `json:"created"`
 	Model   string                      `json:"model"`
 	Choices []ChatCompletionChunkChoice `json:"choices"`
+	Usage   *Usage                      `json:"usage,omitempty"`
 }
@@ -0,0 +1,164 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Built-in entity types.
+const (
+	Email = "email"
+	Phone = "phone"
+	Card  = "card"
+)
+
+var builtinPatterns = map[string]*regexp.Regexp{
+	Email: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	Card:  regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+	Phone: regexp.MustCompile(`(?:\+\d{1,3}[ \-.]?)?(?:\(\d{2,4}\)[ \-.]?|\d{2,4}[ \-.])\d{3,4}[ \-.]?\d{3,4}\b`),
+}
+
+// placeholderPattern matches the placeholders produced by Redactor.
+var placeholderPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_\d+\]`)
+
+type entity struct {
+	name    string
+	pattern *regexp.Regexp
+	// validate rejects pattern matches that are not really the entity, such
+	// as digit runs that fail the card checksum.
+	validate func(string) bool
+}
+
+// Detector finds PII entities in text. It is safe for concurrent use.
+type Detector struct {
+	entities []entity
+}
+
+// NewDetector builds a detector for the named built-in entities plus custom
+// regular expressions keyed by entity name.
+func NewDetector(builtins []string, custom map[string]string) (*Detector, error) {
+	d := &Detector{}
+
+	// Cards go first so their digit runs are not claimed as phone numbers.
+	builtins = slices.Clone(builtins)
+	sort.SliceStable(builtins, func(i, j int) bool { return builtins[i] == Card && builtins[j] != Card })
+	for _, name := range builtins {
+		pattern, ok := builtinPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown PII entity: %s", name)
+		}
+		e := entity{name: name, pattern: pattern}
+		if name == Card {
+			e.validate = luhnValid
+		}
+		d.entities = append(d.entities, e)
+	}
+
+	names := make([]string, 0, len(custom))
+	for name := range custom {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pattern, err := regexp.Compile(custom[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern for PII entity %s: %w", name, err)
+		}
+		d.entities = append(d.entities, entity{name: name, pattern: pattern})
+	}
+	return d, nil
+}
+
+// Redactor replaces PII with numbered placeholders such as [EMAIL_1] and
+// remembers the originals so they can be restored in the model's output.
+// The same value always maps to the same placeholder within one Redactor.
+// A Redactor belongs to a single request and is not safe for concurrent use.
+type Redactor struct {
+	detector     *Detector
+	placeholders map[string]string
+	originals    map[string]string
+	counts       map[string]int
+}
+
+func (d *Detector) NewRedactor() *Redactor {
+	return &Redactor{
+		detector:     d,
+		placeholders: make(map[string]string),
+		originals:    make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+func (r *Redactor) Redact(text string) string {
+	for _, e := range r.detector.entities {
+		text = e.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if e.validate != nil && !e.validate(match) {
+				return match
+			}
+			if placeholder, ok := r.placeholders[match]; ok {
+				return placeholder
+			}
+			r.counts[e.name]++
+			placeholder := fmt.Sprintf("[%s_%d]", placeholderName(e.name), r.counts[e.name])
+			r.placeholders[match] = placeholder
+			r.originals[placeholder] = match
+			return placeholder
+		})
+	}
+	return text
+}
+
+// Restore puts the original values back in place of known placeholders.
+func (r *Redactor) Restore(text string) string {
+	if len(r.originals) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(placeholder string) string {
+		if original, ok := r.originals[placeholder]; ok {
+			return original
+		}
+		return placeholder
+	})
+}
+
+// Redacted reports whether anything has been replaced.
+func (r *Redactor) Redacted() bool {
+	return len(r.originals) > 0
+}
+
+// placeholderName upper-cases an entity name and replaces anything that
+// placeholderPattern would not match.
+func placeholderName(name string) string {
+	return strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z':
+			return c - 'a' + 'A'
+		case c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+			return c
+		}
+		return '_'
+	}, name)
+}
+
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		digit := int(c - '0')
+		if n%2 == 1 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
@@ -0,0 +1,55 @@
+package pii
+
+import "testing"
+
+func TestRedact(t *testing.T) {
+	d, err := NewDetector([]string{Email, Phone, Card}, map[string]string{"employee id": `EMP-\d{4}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"nothing", "hello there", "hello there"},
+		{"email", "mail ada@example.com today", "mail [EMAIL_1] today"},
+		{"phone", "call +1 415-555-0100 now", "call [PHONE_1] now"},
+		{"card", "card 4111 1111 1111 1111 ok", "card [CARD_1] ok"},
+		{"card failing the checksum", "order 1234567890123", "order 1234567890123"},
+		{"same value, same placeholder", "a@b.io, c@d.io, a@b.io", "[EMAIL_1], [EMAIL_2], [EMAIL_1]"},
+		{"custom entity", "badge EMP-1234", "badge [EMPLOYEE_ID_1]"},
+		{"existing placeholder text", "see [EMAIL_1]", "see [EMAIL_1]"},
+	}
+	for _, tt := range tests {
+		r := d.NewRedactor()
+		got := r.Redact(tt.text)
+		if got != tt.want {
+			t.Errorf("%s: Redact(%q) = %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+		if back := r.Restore(got); back != tt.text {
+			t.Errorf("%s: Restore(%q) = %q, want %q", tt.name, got, back, tt.text)
+		}
+	}
+}
+
+func TestRestoreLeavesUnknownPlaceholders(t *testing.T) {
+	d, err := NewDetector([]string{Email}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := d.NewRedactor()
+	r.Redact("ada@example.com")
+	if got := r.Restore("[EMAIL_1] and [EMAIL_2]"); got != "ada@example.com and [EMAIL_2]" {
+		t.Errorf("Restore = %q", got)
+	}
+}
+
+func TestNewDetectorRejectsUnknownEntities(t *testing.T) {
+	if _, err := NewDetector([]string{"passport"}, nil); err == nil {
+		t.Error("unknown built-in entity accepted")
+	}
+	if _, err := NewDetector(nil, map[string]string{"bad": "("}); err == nil {
+		t.Error("invalid custom pattern accepted")
+	}
+}
@@ -0,0 +1,53 @@
+package pii
+
+import "strings"
+
+// maxPlaceholderLen bounds how much text is held back while waiting for a
+// placeholder that may be split across chunks.
+const maxPlaceholderLen = 64
+
+// StreamRestorer restores placeholders in streamed text. A placeholder can
+// arrive split over several chunks, so a trailing "[EMA" is held back until
+// the next chunk shows whether it completes a placeholder.
+type StreamRestorer struct {
+	redactor *Redactor
+	pending  string
+}
+
+func (r *Redactor) NewStreamRestorer() *StreamRestorer {
+	return &StreamRestorer{redactor: r}
+}
+
+// Write returns the restored text that is safe to emit after delta.
+func (s *StreamRestorer) Write(delta string) string {
+	text := s.pending + delta
+	s.pending = ""
+
+	if cut := partialPlaceholderStart(text); cut >= 0 {
+		s.pending = text[cut:]
+		text = text[:cut]
+	}
+	return s.redactor.Restore(text)
+}
+
+// Flush returns whatever is still held back.
+func (s *StreamRestorer) Flush() string {
+	text := s.pending
+	s.pending = ""
+	return s.redactor.Restore(text)
+}
+
+// partialPlaceholderStart returns the index of a trailing, unterminated
+// "[..." that could still grow into a placeholder, or -1.
+func partialPlaceholderStart(text string) int {
+	open := strings.LastIndexByte(text, '[')
+	if open < 0 || len(text)-open > maxPlaceholderLen {
+		return -1
+	}
+	for _, c := range text[open+1:] {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return -1
+		}
+	}
+	return open
+}
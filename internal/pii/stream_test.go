@@ -0,0 +1,73 @@
+package pii
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamRestorer(t *testing.T) {
+	d, err := NewDetector([]string{Email}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		chunks []string
+		// emitted is what Write returns per chunk, before Flush.
+		emitted []string
+		want    string
+	}{
+		{
+			name:    "whole placeholder",
+			chunks:  []string{"hi [EMAIL_1]!"},
+			emitted: []string{"hi ada@example.com!"},
+			want:    "hi ada@example.com!",
+		},
+		{
+			name:    "split placeholder",
+			chunks:  []string{"hi [EMA", "IL_", "1] bye"},
+			emitted: []string{"hi ", "", "ada@example.com bye"},
+			want:    "hi ada@example.com bye",
+		},
+		{
+			name:    "bracket that is not a placeholder",
+			chunks:  []string{"see [", "note] here"},
+			emitted: []string{"see ", "[note] here"},
+			want:    "see [note] here",
+		},
+		{
+			name:    "held back at the end",
+			chunks:  []string{"mail [EMAIL_"},
+			emitted: []string{"mail "},
+			want:    "mail [EMAIL_",
+		},
+		{
+			name:    "long bracketed run is not held",
+			chunks:  []string{"[" + strings.Repeat("A", maxPlaceholderLen)},
+			emitted: []string{"[" + strings.Repeat("A", maxPlaceholderLen)},
+			want:    "[" + strings.Repeat("A", maxPlaceholderLen),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := d.NewRedactor()
+			r.Redact("ada@example.com")
+			s := r.NewStreamRestorer()
+			var out strings.Builder
+			for i, chunk := range tt.chunks {
+				got := s.Write(chunk)
+				if got != tt.emitted[i] {
+					t.Errorf("Write(%q) = %q, want %q", chunk, got, tt.emitted[i])
+				}
+				out.WriteString(got)
+			}
+			out.WriteString(s.Flush())
+			if out.String() != tt.want {
+				t.Errorf("stream = %q, want %q", out.String(), tt.want)
+			}
+			if rest := s.Flush(); rest != "" {
+				t.Errorf("second Flush = %q", rest)
+			}
+		})
+	}
+}
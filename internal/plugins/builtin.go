@@ -0,0 +1,126 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/llm-router/internal/logging"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+)
+
+const logStartKey = "log.start"
+
+// newLogPlugin logs every provider call and its outcome.
+//
+//	{"level": "info"}
+func newLogPlugin(options json.RawMessage, logger *slog.Logger) (providers.Interceptor, error) {
+	var opts struct {
+		Level string `json:"level"`
+	}
+	if err := decodeOptions(options, &opts); err != nil {
+		return providers.Interceptor{}, err
+	}
+	level := logging.ParseLevel(opts.Level)
+
+	return providers.Interceptor{
+		BeforeRequest: func(ctx context.Context, call *providers.Call) error {
+			call.Values[logStartKey] = time.Now()
+			logger.Log(ctx, level, "provider request",
+				slog.String("provider", call.Provider),
+				slog.String("model", call.Request.Model),
+				slog.Bool("stream", call.Stream),
+				slog.Int("messages", len(call.Request.Messages)),
+			)
+			return nil
+		},
+		AfterResponse: func(ctx context.Context, call *providers.Call, resp *models.ChatCompletionResponse) (*models.ChatCompletionResponse, error) {
+			var finishReason string
+			if len(resp.Choices) > 0 {
+				finishReason = resp.Choices[0].FinishReason
+			}
+			logger.Log(ctx, level, "provider response",
+				slog.String("provider", call.Provider),
+				slog.String("model", resp.Model),
+				slog.String("finish_reason", finishReason),
+				slog.Int64("total_tokens", resp.Usage.TotalTokens),
+				slog.Duration("latency", time.Since(call.Values[logStartKey].(time.Time))),
+			)
+			return resp, nil
+		},
+		OnChunk: func(ctx context.Context, call *providers.Call, chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			for _, choice := range chunk.Choices {
+				if choice.FinishReason != nil {
+					logger.Log(ctx, level, "provider stream finished",
+						slog.String("provider", call.Provider),
+						slog.String("model", chunk.Model),
+						slog.String("finish_reason", *choice.FinishReason),
+						slog.Duration("latency", time.Since(call.Values[logStartKey].(time.Time))),
+					)
+				}
+			}
+			return chunk
+		},
+	}, nil
+}
+
+// newModelAliasPlugin rewrites model names before they are sent upstream.
+// The provider has already been chosen from the name the client sent.
+//
+//	{"aliases": {"fast": "gpt-4o-mini"}}
+func newModelAliasPlugin(options json.RawMessage, logger *slog.Logger) (providers.Interceptor, error) {
+	var opts struct {
+		Aliases map[string]string `json:"aliases"`
+	}
+	if err := decodeOptions(options, &opts); err != nil {
+		return providers.Interceptor{}, err
+	}
+	if len(opts.Aliases) == 0 {
+		return providers.Interceptor{}, fmt.Errorf("no aliases configured")
+	}
+
+	return providers.Interceptor{
+		BeforeRequest: func(ctx context.Context, call *providers.Call) error {
+			if model, ok := opts.Aliases[call.Request.Model]; ok {
+				req := *call.Request
+				req.Model = model
+				call.Request = &req
+			}
+			return nil
+		},
+	}, nil
+}
+
+// newDefaultsPlugin fills in sampling parameters the client left unset.
+//
+//	{"temperature": 0.2, "max_tokens": 1024}
+func newDefaultsPlugin(options json.RawMessage, logger *slog.Logger) (providers.Interceptor, error) {
+	var opts struct {
+		Temperature *float64 `json:"temperature"`
+		TopP        *float64 `json:"top_p"`
+		MaxTokens   *int64   `json:"max_tokens"`
+	}
+	if err := decodeOptions(options, &opts); err != nil {
+		return providers.Interceptor{}, err
+	}
+
+	return providers.Interceptor{
+		BeforeRequest: func(ctx context.Context, call *providers.Call) error {
+			req := *call.Request
+			if req.Temperature == nil {
+				req.Temperature = opts.Temperature
+			}
+			if req.TopP == nil {
+				req.TopP = opts.TopP
+			}
+			if req.MaxTokens == nil {
+				req.MaxTokens = opts.MaxTokens
+			}
+			call.Request = &req
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,75 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/llm-router/internal/providers"
+)
+
+// Factory builds an interceptor from the plugin's JSON options, which are
+// empty when none were configured.
+type Factory func(options json.RawMessage, logger *slog.Logger) (providers.Interceptor, error)
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Factory{
+		"log":         newLogPlugin,
+		"model_alias": newModelAliasPlugin,
+		"defaults":    newDefaultsPlugin,
+	}
+)
+
+// Register makes a plugin available under name. It is meant to be called
+// before the server starts, typically from an init function.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, exists := registry[name]; exists {
+		panic("plugins: duplicate plugin " + name)
+	}
+	registry[name] = factory
+}
+
+// Build creates the named plugin's interceptor.
+func Build(name string, options json.RawMessage, logger *slog.Logger) (providers.Interceptor, error) {
+	mu.RLock()
+	factory, ok := registry[name]
+	mu.RUnlock()
+	if !ok {
+		return providers.Interceptor{}, fmt.Errorf("unknown plugin %q (available: %v)", name, Names())
+	}
+
+	interceptor, err := factory(options, logger.With(slog.String("plugin", name)))
+	if err != nil {
+		return providers.Interceptor{}, fmt.Errorf("plugin %s: %w", name, err)
+	}
+	if interceptor.Name == "" {
+		interceptor.Name = name
+	}
+	return interceptor, nil
+}
+
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func decodeOptions(options json.RawMessage, v any) error {
+	if len(options) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(options, v); err != nil {
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	return nil
+}
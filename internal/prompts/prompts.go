@@ -0,0 +1,148 @@
+package prompts
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// Template is a versioned list of messages whose contents are text/template
+// sources rendered with Data.
+type Template struct {
+	ID       string
+	Version  int
+	Messages []models.Message
+}
+
+// Data is what templates can refer to: request variables as {{.Vars.name}},
+// request metadata such as {{.Key}} or {{.Now.Format "2006-01-02"}}, and
+// inbound headers via {{.Header "X-Tenant"}}.
+type Data struct {
+	Vars      map[string]string
+	RequestID string
+	Key       string
+	Route     string
+	Model     string
+	Now       time.Time
+	header    http.Header
+}
+
+func NewData(meta requestmeta.Meta, model string, vars map[string]string) Data {
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	return Data{
+		Vars:      vars,
+		RequestID: meta.RequestID,
+		Key:       meta.Key,
+		Route:     meta.Route,
+		Model:     model,
+		Now:       time.Now(),
+		header:    meta.Header,
+	}
+}
+
+func (d Data) Header(name string) string {
+	return d.header.Get(name)
+}
+
+// Compiled is a parsed template ready to render.
+type Compiled struct {
+	ID       string
+	Version  int
+	messages []compiledMessage
+}
+
+type compiledMessage struct {
+	message models.Message
+	content *template.Template
+}
+
+// Parse compiles a single message content, as used for system prompts.
+func Parse(name, content string) (*template.Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Execute renders tmpl to a string.
+func Execute(tmpl *template.Template, data Data) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func (c *Compiled) Render(data Data) ([]models.Message, error) {
+	messages := make([]models.Message, len(c.messages))
+	for i, m := range c.messages {
+		content, err := Execute(m.content, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render template %s version %d: %w", c.ID, c.Version, err)
+		}
+		messages[i] = m.message
+		messages[i].Content = content
+	}
+	return messages, nil
+}
+
+// Store holds every version of every template.
+type Store struct {
+	templates map[string][]*Compiled
+}
+
+func NewStore(templates []Template) (*Store, error) {
+	s := &Store{templates: make(map[string][]*Compiled)}
+	for _, t := range templates {
+		if t.ID == "" || t.Version <= 0 {
+			return nil, fmt.Errorf("template needs an id and a positive version: %q version %d", t.ID, t.Version)
+		}
+		if len(t.Messages) == 0 {
+			return nil, fmt.Errorf("template %s version %d has no messages", t.ID, t.Version)
+		}
+		if _, err := s.Get(t.ID, t.Version); err == nil {
+			return nil, fmt.Errorf("duplicate template %s version %d", t.ID, t.Version)
+		}
+
+		c := &Compiled{ID: t.ID, Version: t.Version}
+		for i, m := range t.Messages {
+			tmpl, err := Parse(fmt.Sprintf("%s@%d[%d]", t.ID, t.Version, i), m.Content)
+			if err != nil {
+				return nil, err
+			}
+			c.messages = append(c.messages, compiledMessage{message: m, content: tmpl})
+		}
+		s.templates[t.ID] = append(s.templates[t.ID], c)
+	}
+
+	for _, versions := range s.templates {
+		sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	}
+	return s, nil
+}
+
+// Get returns the given version of a template, or the latest for version 0.
+func (s *Store) Get(id string, version int) (*Compiled, error) {
+	versions := s.templates[id]
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("unknown template: %s", id)
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	for _, c := range versions {
+		if c.Version == version {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown version %d of template %s", version, id)
+}
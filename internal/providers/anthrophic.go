@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/liushuangls/go-anthropic/v2"
@@ -10,57 +11,31 @@ import (
 )
 
 type anthropicProvider struct {
-	client *anthropic.Client
+	client      *anthropic.Client
+	logger      *slog.Logger
+	cachePolicy PromptCachePolicy
 }
 
-func NewAntropicProvider(apiKey string) Provider {
-	client := anthropic.NewClient(apiKey)
+func NewAntropicProvider(apiKey string, logger *slog.Logger, cachePolicy PromptCachePolicy, opts ...anthropic.ClientOption) Provider {
+	client := anthropic.NewClient(apiKey, opts...)
 	return &anthropicProvider{
-		client: client,
+		client:      client,
+		logger:      logger.With(slog.String("provider", "anthropic")),
+		cachePolicy: cachePolicy,
 	}
 }
 
-func (p *anthropicProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
-	var anthropicMessages []anthropic.Message
-	var systemMessage string
-	var maxTokens int
-
-	for _, msg := range req.Messages {
-		switch msg.Role {
-		case "user":
-			anthropicMessages = append(anthropicMessages, anthropic.NewUserTextMessage(msg.Content))
-		case "assistant":
-			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantTextMessage(msg.Content))
-		case "system":
-			systemMessage = msg.Content
-
-		default:
-			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
-		}
-	}
-
-	if req.MaxTokens != nil {
-		maxTokens = int(*req.MaxTokens)
-	} else {
-		maxTokens = 1024
-	}
+func (p *anthropicProvider) Name() string {
+	return "anthropic"
+}
 
-	messagesRequest := anthropic.MessagesRequest{
-		Model:         anthropic.Model(req.Model),
-		Messages:      anthropicMessages,
-		MaxTokens:     maxTokens,
-		System:        systemMessage,
-		StopSequences: req.Stop,
-	}
-	if req.Temperature != nil {
-		temp := float32(*req.Temperature)
-		messagesRequest.Temperature = &temp
+func (p *anthropicProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	messagesRequest, err := p.newMessagesRequest(req)
+	if err != nil {
+		return nil, err
 	}
 
-	if req.TopP != nil {
-		topP := float32(*req.TopP)
-		messagesRequest.TopP = &topP
-	}
+	p.logger.DebugContext(ctx, "sending chat completion", slog.String("model", req.Model))
 
 	anthropicResp, err := p.client.CreateMessages(ctx, messagesRequest)
 	if err != nil {
@@ -97,11 +72,7 @@ func (p *anthropicProvider) ChatCompletion(ctx context.Context, req *models.Chat
 		response.Choices[0].FinishReason = "stop"
 	}
 
-	response.Usage = models.Usage{
-		PromptTokens:     int64(anthropicResp.Usage.InputTokens),
-		CompletionTokens: int64(anthropicResp.Usage.OutputTokens),
-		TotalTokens:      int64(anthropicResp.Usage.InputTokens) + int64(anthropicResp.Usage.OutputTokens),
-	}
+	response.Usage = anthropicUsage(anthropicResp.Usage)
 
 	return response, nil
 }
@@ -110,48 +81,13 @@ func (p *anthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 	chunkChan := make(chan *models.ChatCompletionChunk)
 	errorChan := make(chan error, 1) // 버퍼를 주어 오류 발생 시 즉시 반환 가능하도록
 
-	var anthropicMessages []anthropic.Message
-	var systemMessage string
-
-	var maxTokens int
-	if req.MaxTokens != nil {
-		maxTokens = int(*req.MaxTokens)
-	} else {
-		maxTokens = 1024
-	}
-
-	for _, msg := range req.Messages {
-		switch msg.Role {
-		case "user":
-			anthropicMessages = append(anthropicMessages, anthropic.NewUserTextMessage(msg.Content))
-		case "assistant":
-			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantTextMessage(msg.Content))
-		case "system":
-			systemMessage = msg.Content
-		default:
-			errorChan <- fmt.Errorf("unsupported message role: %s", msg.Role)
-			close(chunkChan)
-			return chunkChan, errorChan
-		}
-	}
-
-	baseRequest := anthropic.MessagesRequest{
-		Model:         anthropic.Model(req.Model),
-		Messages:      anthropicMessages,
-		MaxTokens:     maxTokens,
-		System:        systemMessage,
-		StopSequences: req.Stop,
-		Stream:        true, // 스트리밍 요청임을 명시
-	}
-
-	if req.Temperature != nil {
-		temp := float32(*req.Temperature)
-		baseRequest.Temperature = &temp
-	}
-	if req.TopP != nil {
-		topP := float32(*req.TopP)
-		baseRequest.TopP = &topP
+	baseRequest, err := p.newMessagesRequest(req)
+	if err != nil {
+		errorChan <- err
+		close(chunkChan)
+		return chunkChan, errorChan
 	}
+	baseRequest.Stream = true // 스트리밍 요청임을 명시
 
 	streamRequest := anthropic.MessagesStreamRequest{
 		MessagesRequest: baseRequest,
@@ -160,6 +96,8 @@ func (p *anthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 	// 스트림 ID와 모델명을 저장하기 위한 변수 (OnMessageStart에서 설정)
 	var streamID string
 	var streamModel string
+	// message_start는 입력 토큰을, message_delta는 누적 출력 토큰을 전달한다
+	var usage anthropic.MessagesUsage
 
 	go func() {
 		defer close(chunkChan)
@@ -167,11 +105,11 @@ func (p *anthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 		streamRequest.OnMessageStart = func(data anthropic.MessagesEventMessageStartData) {
 			streamID = data.Message.ID
 			streamModel = string(data.Message.Model)
-
+			usage = data.Message.Usage
 		}
 
 		streamRequest.OnContentBlockDelta = func(data anthropic.MessagesEventContentBlockDeltaData) {
-			if *data.Delta.Text != "" {
+			if data.Delta.Text != nil && *data.Delta.Text != "" {
 				chunk := &models.ChatCompletionChunk{
 					ID:      streamID,
 					Object:  "chat.completion.chunk",
@@ -192,8 +130,12 @@ func (p *anthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 		}
 
 		streamRequest.OnMessageDelta = func(data anthropic.MessagesEventMessageDeltaData) {
+			if data.Usage.OutputTokens > 0 {
+				usage.OutputTokens = data.Usage.OutputTokens
+			}
+
 			finishReason := string(data.Delta.StopReason)
-			if finishReason == "" {
+			if finishReason != "" {
 				chunk := &models.ChatCompletionChunk{
 					ID:      streamID,
 					Object:  "chat.completion.chunk",
@@ -212,10 +154,24 @@ func (p *anthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 		}
 
 		streamRequest.OnMessageStop = func(data anthropic.MessagesEventMessageStopData) {
+			streamUsage := anthropicUsage(usage)
+			chunkChan <- &models.ChatCompletionChunk{
+				ID:      streamID,
+				Object:  "chat.completion.chunk",
+				Created: time.Now().Unix(),
+				Model:   streamModel,
+				Choices: []models.ChatCompletionChunkChoice{},
+				Usage:   &streamUsage,
+			}
 		}
 
 		streamRequest.OnError = func(errResp anthropic.ErrorResponse) {
 			if errResp.Error != nil {
+				p.logger.WarnContext(ctx, "stream error event",
+					slog.String("model", req.Model),
+					slog.String("type", string(errResp.Error.Type)),
+					slog.String("message", errResp.Error.Message),
+				)
 				select {
 				case errorChan <- fmt.Errorf("anthropic stream callback error: type=%s, message=%s", errResp.Error.Type, errResp.Error.Message):
 				default:
@@ -223,6 +179,7 @@ func (p *anthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 			}
 		}
 
+		p.logger.DebugContext(ctx, "opening chat completion stream", slog.String("model", req.Model))
 		_, err := p.client.CreateMessagesStream(ctx, streamRequest)
 		if err != nil {
 			select {
@@ -236,3 +193,73 @@ func (p *anthropicProvider) ChatCompletionStream(ctx context.Context, req *model
 
 	return chunkChan, errorChan
 }
+
+func (p *anthropicProvider) newMessagesRequest(req *models.ChatCompletionRequest) (anthropic.MessagesRequest, error) {
+	var anthropicMessages []anthropic.Message
+	var systemParts []anthropic.MessageSystemPart
+
+	for _, msg := range req.Messages {
+		var message anthropic.Message
+		switch msg.Role {
+		case "user":
+			message = anthropic.NewUserTextMessage(msg.Content)
+		case "assistant":
+			message = anthropic.NewAssistantTextMessage(msg.Content)
+		case "system":
+			part := anthropic.NewSystemMessagePart(msg.Content)
+			if msg.CacheControl != nil {
+				part.CacheControl = &anthropic.MessageCacheControl{Type: anthropic.CacheControlType(msg.CacheControl.Type)}
+			}
+			systemParts = append(systemParts, part)
+			continue
+		default:
+			return anthropic.MessagesRequest{}, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+		if msg.CacheControl != nil {
+			message.Content[0].SetCacheControl(anthropic.CacheControlType(msg.CacheControl.Type))
+		}
+		anthropicMessages = append(anthropicMessages, message)
+	}
+
+	maxTokens := 1024
+	if req.MaxTokens != nil {
+		maxTokens = int(*req.MaxTokens)
+	}
+
+	messagesRequest := anthropic.MessagesRequest{
+		Model:         anthropic.Model(req.Model),
+		Messages:      anthropicMessages,
+		MultiSystem:   systemParts,
+		MaxTokens:     maxTokens,
+		StopSequences: req.Stop,
+	}
+	if req.Temperature != nil {
+		temp := float32(*req.Temperature)
+		messagesRequest.Temperature = &temp
+	}
+	if req.TopP != nil {
+		topP := float32(*req.TopP)
+		messagesRequest.TopP = &topP
+	}
+
+	p.cachePolicy.apply(&messagesRequest)
+	return messagesRequest, nil
+}
+
+// anthropicUsage converts Anthropic usage, whose input_tokens excludes
+// tokens read from or written to the prompt cache, to the OpenAI shape where
+// prompt_tokens covers the whole prompt.
+func anthropicUsage(u anthropic.MessagesUsage) models.Usage {
+	prompt := int64(u.InputTokens + u.CacheReadInputTokens + u.CacheCreationInputTokens)
+	usage := models.Usage{
+		PromptTokens:     prompt,
+		CompletionTokens: int64(u.OutputTokens),
+		TotalTokens:      prompt + int64(u.OutputTokens),
+		CacheReadTokens:  int64(u.CacheReadInputTokens),
+		CacheWriteTokens: int64(u.CacheCreationInputTokens),
+	}
+	if u.CacheReadInputTokens > 0 {
+		usage.PromptTokensDetails = &models.PromptTokensDetails{CachedTokens: int64(u.CacheReadInputTokens)}
+	}
+	return usage
+}
@@ -0,0 +1,45 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Embedder turns text into a vector for similarity search.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+type openAIEmbedder struct {
+	client openai.Client
+	model  string
+}
+
+func NewOpenAIEmbedder(apiKey, model string) Embedder {
+	return &openAIEmbedder{
+		client: openai.NewClient(option.WithAPIKey(apiKey)),
+		model:  model,
+	}
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	resp, err := e.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: e.model,
+		Input: openai.EmbeddingNewParamsInputUnion{OfString: openai.String(text)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+	if len(resp.Data) == 0 {
+		return nil, fmt.Errorf("embedding response had no data")
+	}
+
+	vector := make([]float32, len(resp.Data[0].Embedding))
+	for i, v := range resp.Data[0].Embedding {
+		vector[i] = float32(v)
+	}
+	return vector, nil
+}
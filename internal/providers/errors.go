@@ -0,0 +1,93 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/liushuangls/go-anthropic/v2"
+	"github.com/openai/openai-go"
+)
+
+var anthropicErrStatus = map[anthropic.ErrType]int{
+	anthropic.ErrTypeInvalidRequest: http.StatusBadRequest,
+	anthropic.ErrTypeAuthentication: http.StatusUnauthorized,
+	anthropic.ErrTypePermission:     http.StatusForbidden,
+	anthropic.ErrTypeNotFound:       http.StatusNotFound,
+	anthropic.ErrTypeTooLarge:       http.StatusRequestEntityTooLarge,
+	anthropic.ErrTypeRateLimit:      http.StatusTooManyRequests,
+	anthropic.ErrTypeApi:            http.StatusInternalServerError,
+	anthropic.ErrTypeOverloaded:     529,
+}
+
+// StatusCode returns the upstream HTTP status carried by a provider error,
+// or 0 when the error did not come from an upstream response.
+func StatusCode(err error) int {
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return openaiErr.StatusCode
+	}
+
+	var reqErr *anthropic.RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.StatusCode
+	}
+
+	var apiErr *anthropic.APIError
+	if errors.As(err, &apiErr) {
+		return anthropicErrStatus[apiErr.Type]
+	}
+
+	return 0
+}
+
+// ErrorClass buckets an error into a small, fixed set of classes suitable
+// for metric labels and log fields.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	}
+
+	status := StatusCode(err)
+	switch {
+	case status == http.StatusTooManyRequests:
+		return "rate_limit"
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return "auth"
+	case status >= 400 && status < 500:
+		return "invalid_request"
+	case status >= 500:
+		return "upstream"
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return "network"
+	}
+	return "unknown"
+}
+
+// RetryAfter returns the wait requested by an upstream Retry-After header,
+// or 0 when there is none.
+func RetryAfter(err error) time.Duration {
+	var openaiErr *openai.Error
+	if !errors.As(err, &openaiErr) || openaiErr.Response == nil {
+		return 0
+	}
+	value := openaiErr.Response.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
@@ -2,29 +2,72 @@ package providers
 
 import (
 	"fmt"
+	"log/slog"
 	"strings"
+
+	"github.com/liushuangls/go-anthropic/v2"
+	"github.com/openai/openai-go/option"
 )
 
 type ProviderFactory struct {
 	providers map[string]Provider
 }
 
-func NewProviderFactory(apiKeys map[string]string) *ProviderFactory {
+func NewProviderFactory(apiKeys map[string]string, logger *slog.Logger, cachePolicy PromptCachePolicy) *ProviderFactory {
 
 	providers := make(map[string]Provider)
 
 	if apiKey, ok := apiKeys["openai"]; ok {
-		providers["openai"] = NewOpenAIProvider(apiKey)
+		providers["openai"] = NewOpenAIProvider(apiKey, logger)
 	}
 
 	if apiKey, ok := apiKeys["anthropic"]; ok {
-		providers["anthropic"] = NewAntropicProvider(apiKey)
+		providers["anthropic"] = NewAntropicProvider(apiKey, logger, cachePolicy)
 	}
 
 	return &ProviderFactory{
 		providers: providers}
 }
 
+// Register replaces the provider serving name, e.g. with a key pool. It
+// must be called before Use.
+func (f *ProviderFactory) Register(name string, provider Provider) {
+	f.providers[name] = provider
+}
+
+// NewEndpointProvider creates a provider for one API key, optionally at a
+// non-default base URL. The SDK's own retries are turned off so a pool can
+// move a failed request to another member straight away.
+func NewEndpointProvider(name, apiKey, baseURL string, logger *slog.Logger, cachePolicy PromptCachePolicy) (Provider, error) {
+	switch name {
+	case "openai":
+		opts := []option.RequestOption{option.WithMaxRetries(0)}
+		if baseURL != "" {
+			// The SDK appends a missing trailing slash on every request,
+			// which races when requests run concurrently.
+			if !strings.HasSuffix(baseURL, "/") {
+				baseURL += "/"
+			}
+			opts = append(opts, option.WithBaseURL(baseURL))
+		}
+		return NewOpenAIProvider(apiKey, logger, opts...), nil
+	case "anthropic":
+		var opts []anthropic.ClientOption
+		if baseURL != "" {
+			opts = append(opts, anthropic.WithBaseURL(baseURL))
+		}
+		return NewAntropicProvider(apiKey, logger, cachePolicy, opts...), nil
+	}
+	return nil, fmt.Errorf("unknown provider: %s", name)
+}
+
+// Use wraps every registered provider with middleware, e.g. instrumentation.
+func (f *ProviderFactory) Use(middleware func(Provider) Provider) {
+	for name, provider := range f.providers {
+		f.providers[name] = middleware(provider)
+	}
+}
+
 func (f *ProviderFactory) GetProvider(model string) (Provider, error) {
 	providerType := f.getProviderTypeFromModel(model)
 
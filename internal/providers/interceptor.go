@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/llm-router/internal/models"
+)
+
+// Call describes one provider call as it passes through interceptors.
+type Call struct {
+	Provider string
+	Stream   bool
+	// Request is what will be sent upstream. Interceptors that change it
+	// should replace it with a modified copy, since the caller still holds
+	// the original.
+	Request *models.ChatCompletionRequest
+	// Values carries state between the hooks of a single call.
+	Values map[string]any
+}
+
+// Interceptor hooks into provider calls. All hooks are optional.
+type Interceptor struct {
+	Name string
+	// BeforeRequest runs before the call; an error aborts it.
+	BeforeRequest func(ctx context.Context, call *Call) error
+	// AfterResponse sees every successful non-streaming response and returns
+	// the one to pass on.
+	AfterResponse func(ctx context.Context, call *Call, resp *models.ChatCompletionResponse) (*models.ChatCompletionResponse, error)
+	// OnChunk sees every streamed chunk and returns the one to pass on; nil
+	// drops it.
+	OnChunk func(ctx context.Context, call *Call, chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk
+}
+
+type interceptedProvider struct {
+	Provider
+	interceptors []Interceptor
+}
+
+// Intercept returns provider middleware running interceptors in order before
+// the request, and in reverse order on the way back, so the first
+// interceptor is the outermost.
+func Intercept(interceptors ...Interceptor) func(Provider) Provider {
+	return func(p Provider) Provider {
+		return &interceptedProvider{Provider: p, interceptors: interceptors}
+	}
+}
+
+func (p *interceptedProvider) before(ctx context.Context, req *models.ChatCompletionRequest, stream bool) (*Call, error) {
+	call := &Call{
+		Provider: p.Name(),
+		Stream:   stream,
+		Request:  req,
+		Values:   make(map[string]any),
+	}
+	for _, i := range p.interceptors {
+		if i.BeforeRequest == nil {
+			continue
+		}
+		if err := i.BeforeRequest(ctx, call); err != nil {
+			return nil, fmt.Errorf("interceptor %s: %w", i.Name, err)
+		}
+	}
+	return call, nil
+}
+
+func (p *interceptedProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	call, err := p.before(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.Provider.ChatCompletion(ctx, call.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	for n := len(p.interceptors) - 1; n >= 0; n-- {
+		i := p.interceptors[n]
+		if i.AfterResponse == nil {
+			continue
+		}
+		if resp, err = i.AfterResponse(ctx, call, resp); err != nil {
+			return nil, fmt.Errorf("interceptor %s: %w", i.Name, err)
+		}
+	}
+	return resp, nil
+}
+
+func (p *interceptedProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	call, err := p.before(ctx, req, true)
+	if err != nil {
+		chunkCh := make(chan *models.ChatCompletionChunk)
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(chunkCh)
+		return chunkCh, errCh
+	}
+
+	chunkCh, errCh := p.Provider.ChatCompletionStream(ctx, call.Request)
+	return Relay(ctx, chunkCh, errCh, StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			for n := len(p.interceptors) - 1; n >= 0 && chunk != nil; n-- {
+				if onChunk := p.interceptors[n].OnChunk; onChunk != nil {
+					chunk = onChunk(ctx, call, chunk)
+				}
+			}
+			return chunk
+		},
+	})
+}
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"log/slog"
 
 	"github.com/llm-router/internal/models"
 	"github.com/openai/openai-go"
@@ -11,36 +12,32 @@ import (
 
 type OpenAIProvider struct {
 	client openai.Client
+	logger *slog.Logger
 }
 
-func NewOpenAIProvider(apiKey string) Provider {
+func NewOpenAIProvider(apiKey string, logger *slog.Logger, opts ...option.RequestOption) Provider {
 	client := openai.NewClient(
-		option.WithAPIKey(apiKey),
+		append([]option.RequestOption{option.WithAPIKey(apiKey)}, opts...)...,
 	)
 	return &OpenAIProvider{
 		client: client,
+		logger: logger.With(slog.String("provider", "openai")),
 	}
 }
 
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
+
 func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
-	messages := make([]openai.ChatCompletionMessageParamUnion, len(req.Messages))
-	for i, msg := range req.Messages {
-		switch msg.Role {
-		case "user":
-			messages[i] = openai.UserMessage(msg.Content)
-		case "assistant":
-			messages[i] = openai.AssistantMessage(msg.Content)
-		case "system":
-			messages[i] = openai.SystemMessage(msg.Content)
-		default:
-			return nil, fmt.Errorf("unsupported message role: %s", msg.Role)
-		}
+	params, err := newOpenAIParams(req)
+	if err != nil {
+		return nil, err
 	}
 
-	chatCompletion, err := p.client.Chat.Completions.New(context.TODO(), openai.ChatCompletionNewParams{
-		Messages: messages,
-		Model:    req.Model,
-	})
+	p.logger.DebugContext(ctx, "sending chat completion", slog.String("model", req.Model))
+
+	chatCompletion, err := p.client.Chat.Completions.New(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get chat completion: %w", err)
 	}
@@ -48,6 +45,7 @@ func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req *models.ChatCom
 	choices := make([]models.ChatCompletionChoice, len(chatCompletion.Choices))
 	for i, choice := range chatCompletion.Choices {
 		choices[i] = models.ChatCompletionChoice{
+			Index: int(choice.Index),
 			Message: models.ChatMessage{
 				Role:    string(choice.Message.Role),
 				Content: choice.Message.Content,
@@ -62,12 +60,8 @@ func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req *models.ChatCom
 		Created: chatCompletion.Created,
 		Model:   string(chatCompletion.Model),
 		Choices: choices,
-		Usage: models.Usage{
-			PromptTokens:     chatCompletion.Usage.PromptTokens,
-			CompletionTokens: chatCompletion.Usage.CompletionTokens,
-			TotalTokens:      chatCompletion.Usage.TotalTokens,
-		},
-		Error: nil,
+		Usage:   openAIUsage(chatCompletion.Usage),
+		Error:   nil,
 	}, nil
 }
 
@@ -82,35 +76,18 @@ func (p *OpenAIProvider) ChatCompletionStream(
 		defer close(chunkCh)
 		defer close(errCh)
 
-		messages := make([]openai.ChatCompletionMessageParamUnion, len(req.Messages))
-		for i, msg := range req.Messages {
-			switch msg.Role {
-			case "user":
-				messages[i] = openai.UserMessage(msg.Content)
-			case "assistant":
-				messages[i] = openai.AssistantMessage(msg.Content)
-			case "system":
-				messages[i] = openai.SystemMessage(msg.Content)
-			default:
-				errCh <- fmt.Errorf("unsupported role: %s", msg.Role)
-				return
-			}
+		params, err := newOpenAIParams(req)
+		if err != nil {
+			errCh <- err
+			return
 		}
-
-		params := openai.ChatCompletionNewParams{
-			Model:    req.Model,
-			Messages: messages,
-		}
-		if req.Temperature != nil {
-			params.Temperature = openai.Float(*req.Temperature)
-		}
-		if req.TopP != nil {
-			params.TopP = openai.Float(*req.TopP)
-		}
-		if req.MaxTokens != nil {
-			params.MaxTokens = openai.Int(*req.MaxTokens)
+		// Usage is always requested so the router can account for the stream,
+		// whether or not the client asked for it.
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
 		}
 
+		p.logger.DebugContext(ctx, "opening chat completion stream", slog.String("model", req.Model))
 		stream := p.client.Chat.Completions.NewStreaming(ctx, params)
 
 		for stream.Next() {
@@ -144,6 +121,10 @@ func (p *OpenAIProvider) ChatCompletionStream(
 				Model:   string(resp.Model),
 				Choices: choices,
 			}
+			if resp.JSON.Usage.Valid() {
+				usage := openAIUsage(resp.Usage)
+				chunk.Usage = &usage
+			}
 
 			select {
 			case <-ctx.Done():
@@ -160,3 +141,55 @@ func (p *OpenAIProvider) ChatCompletionStream(
 
 	return chunkCh, errCh
 }
+
+func newOpenAIParams(req *models.ChatCompletionRequest) (openai.ChatCompletionNewParams, error) {
+	messages := make([]openai.ChatCompletionMessageParamUnion, len(req.Messages))
+	for i, msg := range req.Messages {
+		switch msg.Role {
+		case "user":
+			messages[i] = openai.UserMessage(msg.Content)
+		case "assistant":
+			messages[i] = openai.AssistantMessage(msg.Content)
+		case "system":
+			messages[i] = openai.SystemMessage(msg.Content)
+		default:
+			return openai.ChatCompletionNewParams{}, fmt.Errorf("unsupported message role: %s", msg.Role)
+		}
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Model:    req.Model,
+		Messages: messages,
+	}
+	if req.Temperature != nil {
+		params.Temperature = openai.Float(*req.Temperature)
+	}
+	if req.TopP != nil {
+		params.TopP = openai.Float(*req.TopP)
+	}
+	if req.MaxTokens != nil {
+		params.MaxTokens = openai.Int(*req.MaxTokens)
+	}
+	if len(req.Stop) > 0 {
+		params.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: req.Stop}
+	}
+	if req.User != "" {
+		params.User = openai.String(req.User)
+	}
+	return params, nil
+}
+
+// openAIUsage maps OpenAI usage, whose prompt tokens already include any
+// served from the automatic prompt cache.
+func openAIUsage(u openai.CompletionUsage) models.Usage {
+	usage := models.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if cached := u.PromptTokensDetails.CachedTokens; cached > 0 {
+		usage.CacheReadTokens = cached
+		usage.PromptTokensDetails = &models.PromptTokensDetails{CachedTokens: cached}
+	}
+	return usage
+}
@@ -0,0 +1,317 @@
+package providers
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+// ErrPoolUnavailable is returned when every member of a pool is cooling
+// down, at capacity, or has already failed the request.
+var ErrPoolUnavailable = errors.New("no pool member available")
+
+// MemberState is the health of one pool member.
+type MemberState int
+
+const (
+	MemberHealthy MemberState = iota
+	// MemberProbing lets a single request through after a cooldown to
+	// decide whether the member has recovered.
+	MemberProbing
+	MemberCoolingDown
+)
+
+// PoolMember is one API key or deployment in a pool. Members with a larger
+// Weight get a larger share of requests; MaxInFlight caps concurrent
+// requests to the member when positive.
+type PoolMember struct {
+	Name        string
+	Weight      float64
+	MaxInFlight int
+	Provider    Provider
+}
+
+type PoolOptions struct {
+	// Cooldown is how long a member sits out after a 429 without a
+	// Retry-After header, or after FailureThreshold consecutive failures.
+	Cooldown         time.Duration
+	FailureThreshold int
+	// OnStateChange is called whenever a member changes state.
+	OnStateChange func(provider, member string, state MemberState)
+}
+
+type poolMember struct {
+	PoolMember
+	inFlight      int
+	failures      int
+	state         MemberState
+	coolUntil     time.Time
+	probing       bool
+	currentWeight float64
+}
+
+// pool spreads requests over several members of the same provider. Each
+// request goes to the least-loaded healthy member, relative to its weight,
+// with smooth weighted round-robin between equally loaded members. A
+// request that fails with a rate limit, auth or upstream error before any
+// output is moved to another member.
+type pool struct {
+	name    string
+	members []*poolMember
+	opts    PoolOptions
+	logger  *slog.Logger
+
+	mu sync.Mutex
+}
+
+func NewPool(name string, members []PoolMember, opts PoolOptions, logger *slog.Logger) Provider {
+	p := &pool{
+		name:   name,
+		opts:   opts,
+		logger: logger.With(slog.String("provider", name)),
+	}
+	for _, m := range members {
+		if m.Weight <= 0 {
+			m.Weight = 1
+		}
+		p.members = append(p.members, &poolMember{PoolMember: m})
+	}
+	for _, m := range p.members {
+		p.notify(m)
+	}
+	return p
+}
+
+func (p *pool) Name() string {
+	return p.name
+}
+
+func (p *pool) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	tried := make([]bool, len(p.members))
+	var lastErr error
+	for {
+		m, err := p.acquire(tried)
+		if err != nil {
+			return nil, cmp.Or(lastErr, err)
+		}
+		resp, err := m.Provider.ChatCompletion(ctx, req)
+		p.release(ctx, m, err)
+		if err != nil && p.failover(ctx, err, tried) {
+			lastErr = err
+			continue
+		}
+		return resp, err
+	}
+}
+
+func (p *pool) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	out := make(chan *models.ChatCompletionChunk)
+	outErr := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErr)
+
+		tried := make([]bool, len(p.members))
+		clientGone := false
+		var lastErr error
+		for {
+			m, err := p.acquire(tried)
+			if err != nil {
+				outErr <- cmp.Or(lastErr, err)
+				return
+			}
+
+			chunkCh, errCh := m.Provider.ChatCompletionStream(ctx, req)
+			started := false
+			var streamErr error
+			for chunkCh != nil {
+				select {
+				case chunk, ok := <-chunkCh:
+					if !ok {
+						chunkCh = nil
+						continue
+					}
+					started = true
+					if clientGone {
+						continue
+					}
+					select {
+					case out <- chunk:
+					case <-ctx.Done():
+						clientGone = true
+					}
+				case err, ok := <-errCh:
+					if !ok {
+						errCh = nil
+						continue
+					}
+					if streamErr == nil {
+						streamErr = err
+					}
+				}
+			}
+			// Same as Relay: only a pending error is read, since some
+			// providers never close the error channel.
+			select {
+			case err, ok := <-errCh:
+				if ok && streamErr == nil {
+					streamErr = err
+				}
+			default:
+			}
+
+			p.release(ctx, m, streamErr)
+			if streamErr != nil && !started && p.failover(ctx, streamErr, tried) {
+				lastErr = streamErr
+				continue
+			}
+			if streamErr != nil {
+				outErr <- streamErr
+			}
+			return
+		}
+	}()
+
+	return out, outErr
+}
+
+// acquire picks the member for the next attempt and counts it as in
+// flight.
+func (p *pool) acquire(tried []bool) (*poolMember, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var candidates []*poolMember
+	var lowest float64
+	for i, m := range p.members {
+		if tried[i] || (m.MaxInFlight > 0 && m.inFlight >= m.MaxInFlight) {
+			continue
+		}
+		switch m.state {
+		case MemberCoolingDown:
+			if now.Before(m.coolUntil) {
+				continue
+			}
+			m.state = MemberProbing
+			p.notify(m)
+			fallthrough
+		case MemberProbing:
+			if m.probing {
+				continue
+			}
+		}
+
+		load := float64(m.inFlight) / m.Weight
+		if len(candidates) == 0 || load < lowest {
+			candidates, lowest = candidates[:0], load
+		}
+		if load == lowest {
+			candidates = append(candidates, m)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%s: %w", p.name, ErrPoolUnavailable)
+	}
+
+	var total float64
+	var best *poolMember
+	for _, m := range candidates {
+		m.currentWeight += m.Weight
+		total += m.Weight
+		if best == nil || m.currentWeight > best.currentWeight {
+			best = m
+		}
+	}
+	best.currentWeight -= total
+
+	for i, m := range p.members {
+		if m == best {
+			tried[i] = true
+		}
+	}
+	best.inFlight++
+	if best.state == MemberProbing {
+		best.probing = true
+	}
+	return best, nil
+}
+
+// release records the outcome of an attempt on m.
+func (p *pool) release(ctx context.Context, m *poolMember, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	m.inFlight--
+	wasProbing := m.probing
+	m.probing = false
+
+	switch {
+	case err == nil:
+		m.failures = 0
+		if m.state != MemberHealthy {
+			m.state = MemberHealthy
+			p.logger.InfoContext(ctx, "pool member recovered", slog.String("member", m.Name))
+			p.notify(m)
+		}
+	case ctx.Err() != nil || !memberFault(err):
+		// Says nothing about the member's health.
+	default:
+		m.failures++
+		cooldown := p.opts.Cooldown
+		status := StatusCode(err)
+		if wait := RetryAfter(err); status == http.StatusTooManyRequests && wait > 0 {
+			cooldown = wait
+		}
+		if status == http.StatusTooManyRequests || status == http.StatusUnauthorized || status == http.StatusForbidden ||
+			wasProbing || m.failures >= p.opts.FailureThreshold {
+			m.state = MemberCoolingDown
+			m.coolUntil = time.Now().Add(cooldown)
+			p.logger.WarnContext(ctx, "pool member cooling down",
+				slog.String("member", m.Name),
+				slog.String("error_class", ErrorClass(err)),
+				slog.Duration("cooldown", cooldown),
+			)
+			p.notify(m)
+		}
+	}
+}
+
+// failover reports whether a failed attempt should be retried on another
+// member.
+func (p *pool) failover(ctx context.Context, err error, tried []bool) bool {
+	if ctx.Err() != nil || !memberFault(err) {
+		return false
+	}
+	for _, t := range tried {
+		if !t {
+			return true
+		}
+	}
+	return false
+}
+
+// memberFault reports whether err is specific to the member that returned
+// it, so another member may succeed.
+func memberFault(err error) bool {
+	switch ErrorClass(err) {
+	case "rate_limit", "auth", "upstream", "network", "timeout":
+		return true
+	}
+	return false
+}
+
+// notify must be called with p.mu held.
+func (p *pool) notify(m *poolMember) {
+	if p.opts.OnStateChange != nil {
+		p.opts.OnStateChange(p.name, m.Name, m.state)
+	}
+}
@@ -0,0 +1,321 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/openai/openai-go"
+)
+
+// fakeProvider is a Provider whose behaviour each test supplies. It counts
+// the calls it receives.
+type fakeProvider struct {
+	name     string
+	complete func(ctx context.Context) (*models.ChatCompletionResponse, error)
+	stream   func(ctx context.Context) (<-chan *models.ChatCompletionChunk, <-chan error)
+	calls    atomic.Int64
+}
+
+func (f *fakeProvider) Name() string {
+	return f.name
+}
+
+func (f *fakeProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	f.calls.Add(1)
+	return f.complete(ctx)
+}
+
+func (f *fakeProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	f.calls.Add(1)
+	return f.stream(ctx)
+}
+
+func answer(member string) func(context.Context) (*models.ChatCompletionResponse, error) {
+	return func(context.Context) (*models.ChatCompletionResponse, error) {
+		return &models.ChatCompletionResponse{Model: member}, nil
+	}
+}
+
+func failWith(err error) func(context.Context) (*models.ChatCompletionResponse, error) {
+	return func(context.Context) (*models.ChatCompletionResponse, error) {
+		return nil, err
+	}
+}
+
+// apiError is an upstream error response with status and, when positive,
+// a Retry-After header.
+func apiError(status int, retryAfter time.Duration) error {
+	req, _ := http.NewRequest(http.MethodPost, "https://api.example.com/v1/chat/completions", nil)
+	resp := &http.Response{StatusCode: status, Header: make(http.Header), Request: req}
+	if retryAfter > 0 {
+		resp.Header.Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+	}
+	return &openai.Error{StatusCode: status, Request: req, Response: resp}
+}
+
+// stateLog records the state changes a pool reports.
+type stateLog struct {
+	mu     sync.Mutex
+	states map[string]MemberState
+}
+
+func (l *stateLog) record(_, member string, state MemberState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.states == nil {
+		l.states = make(map[string]MemberState)
+	}
+	l.states[member] = state
+}
+
+func (l *stateLog) state(member string) MemberState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.states[member]
+}
+
+// members makes each provider a pool member of the same name.
+func members(providers ...*fakeProvider) []PoolMember {
+	var out []PoolMember
+	for _, p := range providers {
+		out = append(out, PoolMember{Name: p.name, Provider: p})
+	}
+	return out
+}
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// untilCalled sends requests until one reaches m, since members with equal
+// load take turns, and returns that request's outcome.
+func untilCalled(t *testing.T, p Provider, m *fakeProvider) (*models.ChatCompletionResponse, error) {
+	t.Helper()
+	before := m.calls.Load()
+	for range 10 {
+		resp, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{})
+		if m.calls.Load() > before {
+			return resp, err
+		}
+	}
+	t.Fatalf("%s was never called", m.name)
+	return nil, nil
+}
+
+func TestPoolFailsOverOnMemberFault(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		failover bool
+	}{
+		{"rate limit", apiError(http.StatusTooManyRequests, 0), true},
+		{"auth", apiError(http.StatusUnauthorized, 0), true},
+		{"upstream", apiError(http.StatusBadGateway, 0), true},
+		{"timeout", context.DeadlineExceeded, true},
+		{"invalid request", apiError(http.StatusBadRequest, 0), false},
+		{"unknown", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeProvider{name: "a", complete: failWith(tt.err)}
+			b := &fakeProvider{name: "b", complete: answer("b")}
+			p := NewPool("openai", members(a, b), PoolOptions{Cooldown: time.Minute, FailureThreshold: 3}, discard)
+
+			resp, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{})
+			if tt.failover {
+				if err != nil || resp.Model != "b" {
+					t.Fatalf("got %v, %v; want b's answer", resp, err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if n := b.calls.Load(); n != 0 {
+				t.Fatalf("b called %d times for an error that is not the member's fault", n)
+			}
+		})
+	}
+}
+
+func TestPoolCooldown(t *testing.T) {
+	failing := atomic.Bool{}
+	failing.Store(true)
+	a := &fakeProvider{name: "a", complete: func(ctx context.Context) (*models.ChatCompletionResponse, error) {
+		if failing.Load() {
+			return nil, apiError(http.StatusTooManyRequests, 0)
+		}
+		return answer("a")(ctx)
+	}}
+	b := &fakeProvider{name: "b", complete: answer("b")}
+	log := &stateLog{}
+	p := NewPool("openai", members(a, b), PoolOptions{Cooldown: 50 * time.Millisecond, FailureThreshold: 3, OnStateChange: log.record}, discard)
+	req := &models.ChatCompletionRequest{}
+
+	if resp, err := p.ChatCompletion(context.Background(), req); err != nil || resp.Model != "b" {
+		t.Fatalf("first request got %v, %v; want b's answer", resp, err)
+	}
+	if log.state("a") != MemberCoolingDown {
+		t.Fatalf("a is %v after a 429, want cooling down", log.state("a"))
+	}
+	for range 3 {
+		if resp, err := p.ChatCompletion(context.Background(), req); err != nil || resp.Model != "b" {
+			t.Fatalf("request during cooldown got %v, %v; want b's answer", resp, err)
+		}
+	}
+	if n := a.calls.Load(); n != 1 {
+		t.Fatalf("a called %d times during its cooldown, want 1", n)
+	}
+
+	// After the cooldown a probe that fails cools a down again at once.
+	time.Sleep(60 * time.Millisecond)
+	if resp, err := untilCalled(t, p, a); err != nil || resp.Model != "b" {
+		t.Fatalf("failed probe got %v, %v; want b's answer", resp, err)
+	}
+	if n := a.calls.Load(); n != 2 || log.state("a") != MemberCoolingDown {
+		t.Fatalf("after a failed probe a was called %d times and is %v", n, log.state("a"))
+	}
+
+	// A probe that succeeds makes a healthy again.
+	failing.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if resp, err := untilCalled(t, p, a); err != nil || resp.Model != "a" {
+		t.Fatalf("probe got %v, %v; want a's answer", resp, err)
+	}
+	if log.state("a") != MemberHealthy {
+		t.Fatalf("a is %v after a successful probe, want healthy", log.state("a"))
+	}
+}
+
+func TestPoolCooldownUsesRetryAfter(t *testing.T) {
+	a := &fakeProvider{name: "a", complete: failWith(apiError(http.StatusTooManyRequests, time.Hour))}
+	b := &fakeProvider{name: "b", complete: answer("b")}
+	p := NewPool("openai", members(a, b), PoolOptions{Cooldown: time.Millisecond, FailureThreshold: 3}, discard)
+
+	p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{})
+	time.Sleep(5 * time.Millisecond)
+	for range 5 {
+		p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{})
+	}
+	if n := a.calls.Load(); n != 1 {
+		t.Fatalf("a called %d times within its Retry-After, want 1", n)
+	}
+}
+
+func TestPoolFailureThreshold(t *testing.T) {
+	a := &fakeProvider{name: "a", complete: failWith(apiError(http.StatusInternalServerError, 0))}
+	log := &stateLog{}
+	p := NewPool("openai", members(a), PoolOptions{Cooldown: time.Minute, FailureThreshold: 3, OnStateChange: log.record}, discard)
+
+	for i := 1; i <= 3; i++ {
+		if _, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{}); StatusCode(err) != http.StatusInternalServerError {
+			t.Fatalf("request %d: err = %v, want the upstream error", i, err)
+		}
+		want := MemberHealthy
+		if i == 3 {
+			want = MemberCoolingDown
+		}
+		if got := log.state("a"); got != want {
+			t.Fatalf("after %d failures a is %v, want %v", i, got, want)
+		}
+	}
+	if _, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{}); !errors.Is(err, ErrPoolUnavailable) {
+		t.Fatalf("err = %v with every member cooling down, want ErrPoolUnavailable", err)
+	}
+}
+
+func TestPoolSingleProbe(t *testing.T) {
+	release := make(chan struct{})
+	probing := make(chan struct{})
+	calls := atomic.Int64{}
+	a := &fakeProvider{name: "a", complete: func(ctx context.Context) (*models.ChatCompletionResponse, error) {
+		if calls.Add(1) == 1 {
+			return nil, apiError(http.StatusTooManyRequests, 0)
+		}
+		close(probing)
+		<-release
+		return answer("a")(ctx)
+	}}
+	b := &fakeProvider{name: "b", complete: answer("b")}
+	p := NewPool("openai", members(a, b), PoolOptions{Cooldown: 10 * time.Millisecond, FailureThreshold: 3}, discard)
+
+	p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{})
+	time.Sleep(20 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for calls.Load() < 2 {
+			p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{})
+		}
+	}()
+	<-probing
+	for range 3 {
+		if resp, err := p.ChatCompletion(context.Background(), &models.ChatCompletionRequest{}); err != nil || resp.Model != "b" {
+			t.Fatalf("request during a probe got %v, %v; want b's answer", resp, err)
+		}
+	}
+	close(release)
+	<-done
+	if n := a.calls.Load(); n != 2 {
+		t.Fatalf("a called %d times, want one failure and one probe", n)
+	}
+}
+
+func TestPoolStreamFailover(t *testing.T) {
+	streamOf := func(err error, words ...string) func(context.Context) (<-chan *models.ChatCompletionChunk, <-chan error) {
+		return func(context.Context) (<-chan *models.ChatCompletionChunk, <-chan error) {
+			chunkCh := make(chan *models.ChatCompletionChunk, len(words))
+			errCh := make(chan error, 1)
+			for _, w := range words {
+				chunkCh <- &models.ChatCompletionChunk{Choices: []models.ChatCompletionChunkChoice{{Delta: models.ChatMessage{Content: w}}}}
+			}
+			if err != nil {
+				errCh <- err
+			}
+			close(chunkCh)
+			close(errCh)
+			return chunkCh, errCh
+		}
+	}
+	read := func(chunkCh <-chan *models.ChatCompletionChunk, errCh <-chan error) (string, error) {
+		var text string
+		for chunk := range chunkCh {
+			text += chunk.Choices[0].Delta.Content
+		}
+		return text, <-errCh
+	}
+	upstream := apiError(http.StatusServiceUnavailable, 0)
+
+	t.Run("before output", func(t *testing.T) {
+		a := &fakeProvider{name: "a", stream: streamOf(upstream)}
+		b := &fakeProvider{name: "b", stream: streamOf(nil, "from ", "b")}
+		p := NewPool("openai", members(a, b), PoolOptions{Cooldown: time.Minute, FailureThreshold: 1}, discard)
+
+		text, err := read(p.ChatCompletionStream(context.Background(), &models.ChatCompletionRequest{}))
+		if err != nil || text != "from b" {
+			t.Fatalf("got %q, %v; want b's stream", text, err)
+		}
+	})
+
+	t.Run("after output", func(t *testing.T) {
+		a := &fakeProvider{name: "a", stream: streamOf(upstream, "from ")}
+		b := &fakeProvider{name: "b", stream: streamOf(nil, "from ", "b")}
+		p := NewPool("openai", members(a, b), PoolOptions{Cooldown: time.Minute, FailureThreshold: 1}, discard)
+
+		text, err := read(p.ChatCompletionStream(context.Background(), &models.ChatCompletionRequest{}))
+		if !errors.Is(err, upstream) || text != "from " {
+			t.Fatalf("got %q, %v; want a's partial stream and its error", text, err)
+		}
+		if n := b.calls.Load(); n != 0 {
+			t.Fatalf("b called %d times after a produced output", n)
+		}
+	})
+}
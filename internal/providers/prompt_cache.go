@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"github.com/liushuangls/go-anthropic/v2"
+	"github.com/llm-router/internal/tokenizer"
+)
+
+// Anthropic accepts at most this many cache_control breakpoints per request.
+const maxCacheBreakpoints = 4
+
+// PromptCachePolicy adds Anthropic prompt caching breakpoints on top of any
+// cache_control hints sent by the client. With Auto set, a system prompt of
+// at least MinTokens is marked, and so is the conversation prefix before the
+// final turn once it reaches MinTokens, so follow-up turns read it from the
+// cache.
+type PromptCachePolicy struct {
+	Auto      bool
+	MinTokens int
+}
+
+func (p PromptCachePolicy) apply(req *anthropic.MessagesRequest) {
+	if !p.Auto {
+		return
+	}
+	used := countBreakpoints(req)
+	count := tokenizer.ForModel(string(req.Model)).Count
+
+	var prefixTokens int64
+	for _, part := range req.MultiSystem {
+		prefixTokens += count(part.Text)
+	}
+	if n := len(req.MultiSystem); n > 0 && used < maxCacheBreakpoints && prefixTokens >= int64(p.MinTokens) {
+		last := &req.MultiSystem[n-1]
+		if last.CacheControl == nil {
+			last.CacheControl = &anthropic.MessageCacheControl{Type: anthropic.CacheControlTypeEphemeral}
+			used++
+		}
+	}
+
+	if len(req.Messages) < 2 || used >= maxCacheBreakpoints {
+		return
+	}
+	for _, msg := range req.Messages[:len(req.Messages)-1] {
+		for _, content := range msg.Content {
+			if content.Text != nil {
+				prefixTokens += count(*content.Text)
+			}
+		}
+	}
+	if prefixTokens < int64(p.MinTokens) {
+		return
+	}
+	prefixEnd := &req.Messages[len(req.Messages)-2]
+	if n := len(prefixEnd.Content); n > 0 && prefixEnd.Content[n-1].CacheControl == nil {
+		prefixEnd.Content[n-1].SetCacheControl()
+	}
+}
+
+func countBreakpoints(req *anthropic.MessagesRequest) int {
+	count := 0
+	for _, part := range req.MultiSystem {
+		if part.CacheControl != nil {
+			count++
+		}
+	}
+	for _, msg := range req.Messages {
+		for _, content := range msg.Content {
+			if content.CacheControl != nil {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,111 @@
+package providers
+
+import (
+	"context"
+
+	"github.com/llm-router/internal/models"
+)
+
+// StreamHooks observe or rewrite a stream passing through Relay. All hooks
+// are optional.
+type StreamHooks struct {
+	// OnChunk sees every chunk and returns the one to forward; nil drops it.
+	OnChunk func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk
+	// OnError sees every non-nil error from the source stream.
+	OnError func(err error)
+	// DropError reports whether an error should be kept from the client,
+	// such as one caused by the hook owner cancelling the source.
+	DropError func(err error) bool
+	// OnEnd runs once the source stream has finished and returns chunks to
+	// forward after it, such as text a rewriting hook still holds back.
+	OnEnd func() []*models.ChatCompletionChunk
+	// OnDone runs once the source stream has finished, before the relayed
+	// channels are closed.
+	OnDone func()
+}
+
+// Relay forwards a provider stream through hooks. The first error is passed
+// on. Once ctx is done nothing more is forwarded, but the source is still
+// drained so hooks see the whole stream and the producer never blocks.
+func Relay(ctx context.Context, chunkCh <-chan *models.ChatCompletionChunk, errCh <-chan error, hooks StreamHooks) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	out := make(chan *models.ChatCompletionChunk)
+	outErr := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErr)
+
+		clientGone := false
+		handleErr := func(err error) {
+			if err == nil {
+				return
+			}
+			if hooks.OnError != nil {
+				hooks.OnError(err)
+			}
+			if clientGone || (hooks.DropError != nil && hooks.DropError(err)) {
+				return
+			}
+			select {
+			case outErr <- err:
+			default:
+			}
+		}
+
+		for chunkCh != nil {
+			select {
+			case chunk, ok := <-chunkCh:
+				if !ok {
+					chunkCh = nil
+					continue
+				}
+				if hooks.OnChunk != nil {
+					chunk = hooks.OnChunk(chunk)
+				}
+				if chunk == nil || clientGone {
+					continue
+				}
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					clientGone = true
+				}
+			case err, ok := <-errCh:
+				if !ok {
+					errCh = nil
+					continue
+				}
+				handleErr(err)
+			}
+		}
+
+		// Providers report a failure before closing the chunk channel, and
+		// some never close the error channel, so only a pending error is read.
+		select {
+		case err, ok := <-errCh:
+			if ok {
+				handleErr(err)
+			}
+		default:
+		}
+
+		if hooks.OnEnd != nil {
+			for _, chunk := range hooks.OnEnd() {
+				if clientGone {
+					break
+				}
+				select {
+				case out <- chunk:
+				case <-ctx.Done():
+					clientGone = true
+				}
+			}
+		}
+
+		if hooks.OnDone != nil {
+			hooks.OnDone()
+		}
+	}()
+
+	return out, outErr
+}
@@ -0,0 +1,104 @@
+package requestmeta
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+const anonymousKey = "anonymous"
+
+// ShadowRoute is the route of the router's own shadow calls. Their key is
+// the client's with ShadowKeySuffix, so usage, metrics and audit entries
+// keep them apart from the client's traffic.
+const (
+	ShadowRoute     = "shadow"
+	ShadowKeySuffix = "/shadow"
+)
+
+// Meta describes who made a request and which route it came in on. Key is a
+// fingerprint of the client's API key, never the key itself. Header is the
+// inbound request header; ResponseHeader lets lower layers add headers to
+// the reply as long as nothing has been written yet.
+type Meta struct {
+	RequestID      string
+	Route          string
+	Key            string
+	Header         http.Header
+	ResponseHeader http.Header
+}
+
+// Anonymous reports whether the request carried no client key.
+func (m Meta) Anonymous() bool {
+	return m.Key == "" || m.Key == anonymousKey
+}
+
+// Shadow returns the metadata of a shadow call mirroring this request.
+func (m Meta) Shadow() Meta {
+	if m.Key == "" {
+		m.Key = anonymousKey
+	}
+	m.Route = ShadowRoute
+	m.Key += ShadowKeySuffix
+	return m
+}
+
+type contextKey struct{}
+
+func WithMeta(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, contextKey{}, meta)
+}
+
+func FromContext(ctx context.Context) Meta {
+	meta, _ := Lookup(ctx)
+	if meta.Key == "" {
+		meta.Key = anonymousKey
+	}
+	return meta
+}
+
+// Lookup reports whether ctx carries request metadata at all.
+func Lookup(ctx context.Context) (Meta, bool) {
+	meta, ok := ctx.Value(contextKey{}).(Meta)
+	return meta, ok
+}
+
+// SetResponseHeader sets a header on the reply to the current request. It
+// is a no-op outside an HTTP request.
+func SetResponseHeader(ctx context.Context, key, value string) {
+	meta, ok := Lookup(ctx)
+	if !ok || meta.ResponseHeader == nil {
+		return
+	}
+	meta.ResponseHeader.Set(key, value)
+}
+
+// NewRequestID returns a random 16-byte hex identifier.
+func NewRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// KeyFingerprint derives a stable, non-reversible identifier for the client
+// key sent in the Authorization or x-api-key header.
+func KeyFingerprint(header http.Header) string {
+	key := strings.TrimSpace(strings.TrimPrefix(header.Get("Authorization"), "Bearer "))
+	if key == "" {
+		key = strings.TrimSpace(header.Get("X-Api-Key"))
+	}
+	if key == "" {
+		return anonymousKey
+	}
+	return FingerprintKey(key)
+}
+
+func FingerprintKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return "key-" + hex.EncodeToString(sum[:])[:12]
+}
@@ -0,0 +1,229 @@
+package routing
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/llm-router/internal/accounting"
+)
+
+// Optimize is what a route minimizes among the targets that meet its
+// constraints.
+type Optimize string
+
+const (
+	OptimizeCost    Optimize = "cost"
+	OptimizeLatency Optimize = "latency"
+)
+
+// DefaultMaxErrorRate is a route's MaxErrorRate when none is set, so a
+// failing target is not picked for answering quickly or cheaply.
+const DefaultMaxErrorRate = 0.5
+
+// Objective picks a target, e.g. "cheapest under 2s p95" is Optimize cost
+// with MaxP95 of two seconds. A zero MaxP95 is not enforced; a zero
+// MaxErrorRate means DefaultMaxErrorRate, and 1 or more disables it.
+type Objective struct {
+	Optimize     Optimize
+	MaxP95       time.Duration
+	MaxErrorRate float64
+}
+
+// Route serves requests for Alias with one of several equivalent Targets.
+type Route struct {
+	Alias     string
+	Targets   []string
+	Objective Objective
+}
+
+type Options struct {
+	// Window is how far back observations count.
+	Window time.Duration
+	// MinSamples is how many observations a target needs before its
+	// constraints are enforced; until then it is assumed to meet them.
+	MinSamples int
+	// ExploreRate is the share of requests sent to a random target so
+	// every target's scores stay current.
+	ExploreRate float64
+}
+
+// Router picks targets for aliases from rolling latency, error rate and
+// list price. Streaming and non-streaming requests are scored apart: latency
+// is time to first token for streams and the whole response otherwise.
+type Router struct {
+	routes map[string]Route
+	stats  map[string]*targetStats
+	opts   Options
+}
+
+func NewRouter(routes []Route, opts Options) (*Router, error) {
+	r := &Router{
+		routes: make(map[string]Route, len(routes)),
+		stats:  make(map[string]*targetStats),
+		opts:   opts,
+	}
+	for _, route := range routes {
+		if len(route.Targets) == 0 {
+			return nil, fmt.Errorf("route %s has no targets", route.Alias)
+		}
+		switch route.Objective.Optimize {
+		case "":
+			route.Objective.Optimize = OptimizeCost
+		case OptimizeCost, OptimizeLatency:
+		default:
+			return nil, fmt.Errorf("route %s: unknown objective %q", route.Alias, route.Objective.Optimize)
+		}
+		if route.Objective.MaxErrorRate == 0 {
+			route.Objective.MaxErrorRate = DefaultMaxErrorRate
+		}
+		if _, ok := r.routes[route.Alias]; ok {
+			return nil, fmt.Errorf("duplicate route for %s", route.Alias)
+		}
+		r.routes[route.Alias] = route
+		for _, target := range route.Targets {
+			if r.stats[target] == nil {
+				r.stats[target] = &targetStats{}
+			}
+		}
+	}
+	return r, nil
+}
+
+// Pick returns the target to use for a streaming or non-streaming request
+// for model, or false if model is not a routed alias.
+func (r *Router) Pick(model string, stream bool) (string, bool) {
+	route, ok := r.routes[model]
+	if !ok {
+		return "", false
+	}
+	if len(route.Targets) > 1 && rand.Float64() < r.opts.ExploreRate {
+		return route.Targets[rand.IntN(len(route.Targets))], true
+	}
+	for _, score := range r.score(route, stream) {
+		if score.Selected {
+			return score.Model, true
+		}
+	}
+	return route.Targets[0], true
+}
+
+// Observe records the outcome of a streaming or non-streaming request sent
+// to target.
+func (r *Router) Observe(target string, stream bool, latency time.Duration, failed bool) {
+	if s, ok := r.stats[target]; ok {
+		s.of(stream).add(sample{latency: latency, failed: failed})
+	}
+}
+
+// TargetScore is a target's live standing within a route.
+type TargetScore struct {
+	Model string `json:"model"`
+	Summary
+	P50MS int64 `json:"p50_ms"`
+	P95MS int64 `json:"p95_ms"`
+	// PricePerMTok blends input and output list prices at a 3:1 ratio.
+	PricePerMTok *float64 `json:"price_per_mtok"`
+	Eligible     bool     `json:"eligible"`
+	Selected     bool     `json:"selected"`
+}
+
+type RouteScores struct {
+	Alias        string        `json:"alias"`
+	Stream       bool          `json:"stream"`
+	Optimize     Optimize      `json:"optimize"`
+	MaxP95MS     int64         `json:"max_p95_ms,omitempty"`
+	MaxErrorRate float64       `json:"max_error_rate,omitempty"`
+	Targets      []TargetScore `json:"targets"`
+}
+
+// Scores reports every route's targets as Pick currently sees them, for
+// non-streaming and then streaming requests.
+func (r *Router) Scores() []RouteScores {
+	var out []RouteScores
+	for _, route := range r.routes {
+		for _, stream := range []bool{false, true} {
+			out = append(out, RouteScores{
+				Alias:        route.Alias,
+				Stream:       stream,
+				Optimize:     route.Objective.Optimize,
+				MaxP95MS:     route.Objective.MaxP95.Milliseconds(),
+				MaxErrorRate: route.Objective.MaxErrorRate,
+				Targets:      r.score(route, stream),
+			})
+		}
+	}
+	slices.SortStableFunc(out, func(a, b RouteScores) int { return strings.Compare(a.Alias, b.Alias) })
+	return out
+}
+
+// score evaluates route's targets and marks the one Pick would choose: the
+// best eligible target by the objective, or if none is eligible, the one
+// with the lowest error rate and then latency.
+func (r *Router) score(route Route, stream bool) []TargetScore {
+	scores := make([]TargetScore, len(route.Targets))
+	for i, target := range route.Targets {
+		sum := r.stats[target].of(stream).summary(r.opts.Window)
+		score := TargetScore{
+			Model:   target,
+			Summary: sum,
+			P50MS:   sum.P50.Milliseconds(),
+			P95MS:   sum.P95.Milliseconds(),
+		}
+		if price, ok := accounting.PriceFor(target); ok {
+			blended := math.Round((3*price.Input+price.Output)/4*1e4) / 1e4
+			score.PricePerMTok = &blended
+		}
+		score.Eligible = sum.Samples < r.opts.MinSamples ||
+			((route.Objective.MaxP95 == 0 || sum.P95 <= route.Objective.MaxP95) &&
+				sum.ErrorRate <= route.Objective.MaxErrorRate)
+		scores[i] = score
+	}
+
+	best := -1
+	for i, score := range scores {
+		if score.Eligible && (best < 0 || better(route.Objective.Optimize, score, scores[best])) {
+			best = i
+		}
+	}
+	if best < 0 {
+		for i, score := range scores {
+			if best < 0 || score.ErrorRate < scores[best].ErrorRate ||
+				(score.ErrorRate == scores[best].ErrorRate && score.P95 < scores[best].P95) {
+				best = i
+			}
+		}
+	}
+	scores[best].Selected = true
+	return scores
+}
+
+// better reports whether a beats b under optimize. Both are weighed per
+// successful answer, since a failed request is paid for in time or money
+// and then retried. Ties keep the earlier target, so the configured order
+// is the final tie-breaker.
+func better(optimize Optimize, a, b TargetScore) bool {
+	if optimize == OptimizeLatency {
+		return perSuccess(float64(a.P95), a) < perSuccess(float64(b.P95), b)
+	}
+	return perSuccess(price(a), a) < perSuccess(price(b), b)
+}
+
+// perSuccess scales v by the attempts s needs on average for one success.
+func perSuccess(v float64, s TargetScore) float64 {
+	if s.ErrorRate >= 1 {
+		return math.Inf(1)
+	}
+	return v / (1 - s.ErrorRate)
+}
+
+// price treats targets with unknown prices as the most expensive.
+func price(s TargetScore) float64 {
+	if s.PricePerMTok == nil {
+		return math.Inf(1)
+	}
+	return *s.PricePerMTok
+}
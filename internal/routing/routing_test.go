@@ -0,0 +1,222 @@
+package routing
+
+import (
+	"testing"
+	"time"
+)
+
+// outcomes is what a target has served: ok requests at latency, then
+// failed ones that took failLatency.
+type outcomes struct {
+	ok          int
+	latency     time.Duration
+	failed      int
+	failLatency time.Duration
+}
+
+func TestScore(t *testing.T) {
+	const ms = time.Millisecond
+	tests := []struct {
+		name      string
+		objective Objective
+		targets   []string
+		outcomes  map[string]outcomes
+		want      string
+	}{
+		{
+			name:      "cheapest without samples",
+			objective: Objective{Optimize: OptimizeCost},
+			targets:   []string{"gpt-4o", "gpt-4o-mini"},
+			want:      "gpt-4o-mini",
+		},
+		{
+			name:      "unknown price loses on cost",
+			objective: Objective{Optimize: OptimizeCost},
+			targets:   []string{"in-house", "gpt-4o"},
+			want:      "gpt-4o",
+		},
+		{
+			name:      "fastest",
+			objective: Objective{Optimize: OptimizeLatency},
+			targets:   []string{"gpt-4o", "gpt-4o-mini"},
+			outcomes: map[string]outcomes{
+				"gpt-4o":      {ok: 20, latency: 900 * ms},
+				"gpt-4o-mini": {ok: 20, latency: 300 * ms},
+			},
+			want: "gpt-4o-mini",
+		},
+		{
+			name:      "cheapest under max p95",
+			objective: Objective{Optimize: OptimizeCost, MaxP95: 500 * ms},
+			targets:   []string{"gpt-4o-mini", "gpt-4o"},
+			outcomes: map[string]outcomes{
+				"gpt-4o":      {ok: 20, latency: 300 * ms},
+				"gpt-4o-mini": {ok: 20, latency: 900 * ms},
+			},
+			want: "gpt-4o",
+		},
+		{
+			name:      "constraints wait for min samples",
+			objective: Objective{Optimize: OptimizeCost, MaxP95: 500 * ms},
+			targets:   []string{"gpt-4o", "gpt-4o-mini"},
+			outcomes: map[string]outcomes{
+				"gpt-4o":      {ok: 20, latency: 300 * ms},
+				"gpt-4o-mini": {ok: 5, latency: 900 * ms},
+			},
+			want: "gpt-4o-mini",
+		},
+		{
+			name:      "always failing target does not win on latency",
+			objective: Objective{Optimize: OptimizeLatency},
+			targets:   []string{"gpt-4o-mini", "gpt-4o"},
+			outcomes: map[string]outcomes{
+				"gpt-4o-mini": {failed: 20, failLatency: 5 * ms},
+				"gpt-4o":      {ok: 20, latency: 800 * ms},
+			},
+			want: "gpt-4o",
+		},
+		{
+			name:      "always failing target does not win on cost",
+			objective: Objective{Optimize: OptimizeCost},
+			targets:   []string{"gpt-4o", "gpt-4o-mini"},
+			outcomes: map[string]outcomes{
+				"gpt-4o-mini": {failed: 20, failLatency: 5 * ms},
+				"gpt-4o":      {ok: 20, latency: 800 * ms},
+			},
+			want: "gpt-4o",
+		},
+		{
+			name:      "failures count as worst case latency",
+			objective: Objective{Optimize: OptimizeLatency, MaxErrorRate: 1},
+			targets:   []string{"gpt-4o-mini", "gpt-4o"},
+			outcomes: map[string]outcomes{
+				"gpt-4o-mini": {ok: 16, latency: 100 * ms, failed: 4, failLatency: 2000 * ms},
+				"gpt-4o":      {ok: 20, latency: 800 * ms},
+			},
+			want: "gpt-4o",
+		},
+		{
+			name:      "error rate weighs on cost",
+			objective: Objective{Optimize: OptimizeCost},
+			targets:   []string{"claude-3-haiku", "gpt-4o-mini"},
+			outcomes: map[string]outcomes{
+				"gpt-4o-mini":    {ok: 10, latency: 100 * ms, failed: 10, failLatency: 100 * ms},
+				"claude-3-haiku": {ok: 20, latency: 100 * ms},
+			},
+			want: "claude-3-haiku",
+		},
+		{
+			name:      "none eligible picks lowest error rate",
+			objective: Objective{Optimize: OptimizeCost, MaxP95: 100 * ms},
+			targets:   []string{"gpt-4o-mini", "gpt-4o"},
+			outcomes: map[string]outcomes{
+				"gpt-4o-mini": {ok: 15, latency: 900 * ms, failed: 5, failLatency: 900 * ms},
+				"gpt-4o":      {ok: 20, latency: 900 * ms},
+			},
+			want: "gpt-4o",
+		},
+		{
+			name:      "ties keep configured order",
+			objective: Objective{Optimize: OptimizeLatency},
+			targets:   []string{"claude-3-7-sonnet", "claude-3-5-sonnet"},
+			outcomes: map[string]outcomes{
+				"claude-3-7-sonnet": {ok: 20, latency: 500 * ms},
+				"claude-3-5-sonnet": {ok: 20, latency: 500 * ms},
+			},
+			want: "claude-3-7-sonnet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewRouter([]Route{{Alias: "alias", Targets: tt.targets, Objective: tt.objective}},
+				Options{Window: time.Minute, MinSamples: 20})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for target, o := range tt.outcomes {
+				for range o.ok {
+					r.Observe(target, false, o.latency, false)
+				}
+				for range o.failed {
+					r.Observe(target, false, o.failLatency, true)
+				}
+			}
+
+			var selected []string
+			for _, score := range r.score(r.routes["alias"], false) {
+				if score.Selected {
+					selected = append(selected, score.Model)
+				}
+			}
+			if len(selected) != 1 || selected[0] != tt.want {
+				t.Fatalf("selected %v, want %s", selected, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryCountsFailuresAsWorstCase(t *testing.T) {
+	var s stats
+	for i := range 19 {
+		s.add(sample{latency: time.Duration(i+1) * time.Millisecond})
+	}
+	s.add(sample{latency: time.Millisecond, failed: true})
+
+	sum := s.summary(time.Minute)
+	if sum.ErrorRate != 0.05 {
+		t.Errorf("error rate = %v, want 0.05", sum.ErrorRate)
+	}
+	if sum.P95 != 19*time.Millisecond {
+		t.Errorf("p95 = %v, want the slowest request, 19ms", sum.P95)
+	}
+
+	var failing stats
+	failing.add(sample{latency: 3 * time.Millisecond, failed: true})
+	if sum := failing.summary(time.Minute); sum.P50 != 3*time.Millisecond || sum.ErrorRate != 1 {
+		t.Errorf("all failed: %+v", sum)
+	}
+}
+
+func TestStreamingScoredApart(t *testing.T) {
+	r, err := NewRouter([]Route{{
+		Alias:     "alias",
+		Targets:   []string{"gpt-4o", "gpt-4o-mini"},
+		Objective: Objective{Optimize: OptimizeLatency},
+	}}, Options{Window: time.Minute, MinSamples: 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// gpt-4o-mini streams its first token fast but is slow to finish a
+	// whole response; gpt-4o is the other way round.
+	for range 20 {
+		r.Observe("gpt-4o-mini", true, 50*time.Millisecond, false)
+		r.Observe("gpt-4o-mini", false, 900*time.Millisecond, false)
+		r.Observe("gpt-4o", true, 300*time.Millisecond, false)
+		r.Observe("gpt-4o", false, 400*time.Millisecond, false)
+	}
+
+	if got, _ := r.Pick("alias", true); got != "gpt-4o-mini" {
+		t.Errorf("stream picked %s, want gpt-4o-mini", got)
+	}
+	if got, _ := r.Pick("alias", false); got != "gpt-4o" {
+		t.Errorf("completion picked %s, want gpt-4o", got)
+	}
+}
+
+func TestSummaryReused(t *testing.T) {
+	var s stats
+	s.add(sample{latency: time.Millisecond})
+	if sum := s.summary(time.Minute); sum.Samples != 1 {
+		t.Fatalf("samples = %d, want 1", sum.Samples)
+	}
+
+	s.add(sample{latency: time.Millisecond})
+	if sum := s.summary(time.Minute); sum.Samples != 1 {
+		t.Errorf("samples = %d, want the summary reused within %v", sum.Samples, summaryInterval)
+	}
+
+	s.cachedAt = s.cachedAt.Add(-summaryInterval)
+	if sum := s.summary(time.Minute); sum.Samples != 2 {
+		t.Errorf("samples = %d, want 2 once the summary is stale", sum.Samples)
+	}
+}
@@ -0,0 +1,118 @@
+package routing
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxSamples bounds the memory kept per target regardless of the window.
+const maxSamples = 1000
+
+// summaryInterval is how long a summary is reused before the samples are
+// summarized again, so picking a target does not sort them every request.
+const summaryInterval = time.Second
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+	failed  bool
+}
+
+// targetStats keeps a target's streaming and non-streaming outcomes apart,
+// since streamed latency is time to first token and the rest is the whole
+// response.
+type targetStats struct {
+	completion stats
+	stream     stats
+}
+
+func (t *targetStats) of(stream bool) *stats {
+	if stream {
+		return &t.stream
+	}
+	return &t.completion
+}
+
+// stats is a rolling window of outcomes for one target.
+type stats struct {
+	mu       sync.Mutex
+	samples  []sample
+	cached   Summary
+	cachedAt time.Time
+}
+
+func (s *stats) add(smp sample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	smp.at = time.Now()
+	s.samples = append(s.samples, smp)
+	if len(s.samples) > maxSamples {
+		s.samples = s.samples[len(s.samples)-maxSamples:]
+	}
+}
+
+// Summary describes a target's recent outcomes. Failed requests count as
+// slower than any other in the window, so a failing target's percentiles
+// are its worst case rather than its fastest errors.
+type Summary struct {
+	Samples   int           `json:"samples"`
+	P50       time.Duration `json:"-"`
+	P95       time.Duration `json:"-"`
+	ErrorRate float64       `json:"error_rate"`
+}
+
+// summary summarizes the samples within window, reusing the last summary
+// for up to summaryInterval.
+func (s *stats) summary(window time.Duration) Summary {
+	s.mu.Lock()
+	now := time.Now()
+	if now.Sub(s.cachedAt) < summaryInterval {
+		defer s.mu.Unlock()
+		return s.cached
+	}
+	i, _ := slices.BinarySearchFunc(s.samples, now.Add(-window), func(smp sample, t time.Time) int {
+		return smp.at.Compare(t)
+	})
+	s.samples = s.samples[i:]
+	recent := slices.Clone(s.samples)
+	s.mu.Unlock()
+
+	sum := summarize(recent)
+	s.mu.Lock()
+	s.cached, s.cachedAt = sum, now
+	s.mu.Unlock()
+	return sum
+}
+
+func summarize(recent []sample) Summary {
+	sum := Summary{Samples: len(recent)}
+	if len(recent) == 0 {
+		return sum
+	}
+	var latencies []time.Duration
+	var failed int
+	var worst time.Duration
+	for _, smp := range recent {
+		worst = max(worst, smp.latency)
+		if smp.failed {
+			failed++
+			continue
+		}
+		latencies = append(latencies, smp.latency)
+	}
+	slices.Sort(latencies)
+	for range failed {
+		latencies = append(latencies, worst)
+	}
+	sum.ErrorRate = float64(failed) / float64(len(recent))
+	sum.P50 = percentile(latencies, 0.50)
+	sum.P95 = percentile(latencies, 0.95)
+	return sum
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
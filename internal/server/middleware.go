@@ -0,0 +1,85 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// requestMeta attaches the request ID, route and client key fingerprint to
+// the request context so lower layers can label metrics and logs with them.
+// A caller-supplied X-Request-ID is kept; otherwise one is generated.
+func requestMeta() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if requestID == "" {
+			requestID = requestmeta.NewRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		meta := requestmeta.Meta{
+			RequestID:      requestID,
+			Route:          c.FullPath(),
+			Key:            requestmeta.KeyFingerprint(c.Request.Header),
+			Header:         c.Request.Header,
+			ResponseHeader: c.Writer.Header(),
+		}
+		c.Request = c.Request.WithContext(requestmeta.WithMeta(c.Request.Context(), meta))
+		c.Next()
+	}
+}
+
+// accessLog replaces gin's default logger with one structured line per
+// request.
+func accessLog(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		logger.Log(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// recovery logs panics from handlers instead of writing them to stderr.
+func recovery(logger *slog.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, err any) {
+		logger.ErrorContext(c.Request.Context(), "panic recovered", slog.Any("panic", err))
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// adminAuth requires the admin key as a bearer token. Admin routes are open
+// when no key is configured, like /metrics.
+func adminAuth(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key == "" {
+			c.Next()
+			return
+		}
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": "Unauthorized",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,11 +1,13 @@
 package server
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
 	"github.com/llm-router/internal/handlers"
 )
 
-func RegisterRoutes(engine *gin.Engine, llmHandler *handlers.LLMHandler) {
+func RegisterRoutes(engine *gin.Engine, llmHandler *handlers.LLMHandler, sessionHandler *handlers.SessionHandler, jobHandler *handlers.JobHandler, batchHandler *handlers.BatchHandler, adminHandler *handlers.AdminHandler, adminKey string, metricsHandler http.Handler) {
 
 	// Register health check route
 	engine.GET("/_health", func(c *gin.Context) {
@@ -14,6 +16,42 @@ func RegisterRoutes(engine *gin.Engine, llmHandler *handlers.LLMHandler) {
 		})
 	})
 
+	// Register Prometheus metrics route
+	engine.GET("/metrics", gin.WrapH(metricsHandler))
+
 	// Register LLM chat completion route
 	engine.POST("/v1/chat/completions", llmHandler.HandleChatCompletion)
+
+	// Register token counting route
+	engine.POST("/v1/tokenize", llmHandler.HandleTokenize)
+
+	// Register session routes when a session store is configured
+	if sessionHandler != nil {
+		engine.POST("/v1/sessions", sessionHandler.HandleCreate)
+		engine.GET("/v1/sessions/:id", sessionHandler.HandleGet)
+		engine.POST("/v1/sessions/:id/messages", sessionHandler.HandleAppend)
+		engine.DELETE("/v1/sessions/:id", sessionHandler.HandleDelete)
+	}
+
+	// Register async job routes when jobs are enabled
+	if jobHandler != nil {
+		engine.POST("/v1/jobs", jobHandler.HandleCreate)
+		engine.GET("/v1/jobs/:id", jobHandler.HandleGet)
+		engine.POST("/v1/jobs/:id/cancel", jobHandler.HandleCancel)
+	}
+
+	// Register file and batch routes when batches are enabled
+	if batchHandler != nil {
+		engine.POST("/v1/files", batchHandler.HandleUploadFile)
+		engine.GET("/v1/files/:id", batchHandler.HandleGetFile)
+		engine.GET("/v1/files/:id/content", batchHandler.HandleFileContent)
+		engine.POST("/v1/batches", batchHandler.HandleCreate)
+		engine.GET("/v1/batches", batchHandler.HandleList)
+		engine.GET("/v1/batches/:id", batchHandler.HandleGet)
+		engine.POST("/v1/batches/:id/cancel", batchHandler.HandleCancel)
+	}
+
+	// Register admin routes
+	admin := engine.Group("/admin", adminAuth(adminKey))
+	admin.GET("/routing/scores", adminHandler.HandleRoutingScores)
 }
@@ -1,25 +1,56 @@
 package server
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/llm-router/internal/accounting"
+	"github.com/llm-router/internal/audit"
+	"github.com/llm-router/internal/batches"
+	"github.com/llm-router/internal/cache"
+	"github.com/llm-router/internal/classifier"
 	"github.com/llm-router/internal/config"
+	"github.com/llm-router/internal/contextwindow"
+	"github.com/llm-router/internal/experiments"
+	"github.com/llm-router/internal/guardrails"
 	"github.com/llm-router/internal/handlers"
+	"github.com/llm-router/internal/jobs"
+	"github.com/llm-router/internal/logging"
+	"github.com/llm-router/internal/metrics"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/pii"
+	"github.com/llm-router/internal/plugins"
+	"github.com/llm-router/internal/prompts"
 	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/routing"
 	"github.com/llm-router/internal/services"
+	"github.com/llm-router/internal/sessions"
+	"github.com/llm-router/internal/shadow"
+	"github.com/llm-router/internal/tracing"
 )
 
 type Server struct {
-	engine     *gin.Engine
-	httpServer *http.Server
-	cfg        *config.Config
+	engine          *gin.Engine
+	httpServer      *http.Server
+	cfg             *config.Config
+	logger          *slog.Logger
+	shutdownTracing func(context.Context) error
+	closers         []io.Closer
 }
 
 func NewServer() (*Server, error) {
-	cfg := config.LoadConfig()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
 
 	if cfg.LogLevel == "debug" {
 		gin.SetMode(gin.DebugMode)
@@ -27,25 +58,491 @@ func NewServer() (*Server, error) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	engine := gin.Default()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracingEndpoint, cfg.ServiceName)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	slog.SetDefault(logger)
+
+	engine := gin.New()
+	engine.Use(tracing.Middleware(), requestMeta(), accessLog(logger), recovery(logger))
+
+	m := metrics.New()
+
+	cachePolicy := providers.PromptCachePolicy{
+		Auto:      cfg.PromptCachePolicy == "auto",
+		MinTokens: cfg.PromptCacheMinTokens,
+	}
+	providerFactory := providers.NewProviderFactory(cfg.APIKeys, logger, cachePolicy)
+	for name, pool := range cfg.File.KeyPools {
+		provider, err := keyPool(name, pool, m, logger, cachePolicy)
+		if err != nil {
+			return nil, err
+		}
+		providerFactory.Register(name, provider)
+	}
+	providerFactory.Use(m.InstrumentProvider)
+	providerFactory.Use(tracing.TraceProvider)
+
+	if len(cfg.File.Plugins) > 0 {
+		var interceptors []providers.Interceptor
+		for _, plugin := range cfg.File.Plugins {
+			interceptor, err := plugins.Build(plugin.Name, plugin.Options, logger)
+			if err != nil {
+				return nil, err
+			}
+			interceptors = append(interceptors, interceptor)
+		}
+		providerFactory.Use(providers.Intercept(interceptors...))
+	}
+
+	recorders := accounting.Multi{accounting.NewLedger(), m}
+	var closers []io.Closer
+
+	var auditLogger *audit.Logger
+	if cfg.Audit.Sink != "" {
+		sink, err := audit.OpenSink(cfg.Audit.Sink, cfg.Audit.Path, int64(cfg.Audit.MaxSizeMB)<<20, cfg.Audit.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		auditLogger = audit.NewLogger(sink, audit.Options{
+			SampleRate: cfg.Audit.SampleRate,
+			Redact:     cfg.Audit.Redact,
+		}, logger)
+		recorders = append(recorders, auditLogger)
+		closers = append(closers, auditLogger)
+	}
+
+	llmService := services.NewLLMService(providerFactory, recorders, logger)
+
+	// Context trimming sits right above the providers so it sees the model
+	// that routing, hedging, auto, ensembles and experiments settled on.
+	if cfg.Context.Strategy != "off" {
+		strategy, ok := contextwindow.ParseStrategy(cfg.Context.Strategy)
+		if !ok {
+			return nil, fmt.Errorf("unknown context strategy: %s", cfg.Context.Strategy)
+		}
+		llmService = services.NewContextWindowService(llmService, services.ContextWindowOptions{
+			Strategy:      strategy,
+			KeepLast:      cfg.Context.KeepLast,
+			ReserveTokens: int64(cfg.Context.ReserveTokens),
+			SummaryModel:  cfg.Context.SummaryModel,
+			Limits:        cfg.File.ContextWindows,
+		}, logger)
+	}
+
+	var router *routing.Router
+	if len(cfg.File.Routing.Routes) > 0 {
+		if router, err = newRouter(cfg.File.Routing); err != nil {
+			return nil, err
+		}
+		llmService = services.NewRoutingService(llmService, router, logger)
+	}
 
-	providerFactory := providers.NewProviderFactory(
-		cfg.APIKeys,
-	)
-	llmService := services.NewLLMService(providerFactory)
+	if len(cfg.File.Hedging) > 0 {
+		var policies []services.HedgePolicy
+		for _, p := range cfg.File.Hedging {
+			if p.DelayMS <= 0 {
+				return nil, fmt.Errorf("hedging policy needs a positive delay_ms")
+			}
+			policies = append(policies, services.HedgePolicy{
+				Matches:     p.Match.Matches,
+				Delay:       time.Duration(p.DelayMS) * time.Millisecond,
+				BackupModel: p.BackupModel,
+			})
+		}
+		llmService = services.NewHedgingService(llmService, policies, m.RecordHedge)
+	}
 
-	llmHandler, err := handlers.NewLLMHandler(llmService)
+	if len(cfg.File.Shadow) > 0 {
+		var policies []services.ShadowPolicy
+		for _, p := range cfg.File.Shadow {
+			if p.Model == "" {
+				return nil, fmt.Errorf("shadow policy needs a model")
+			}
+			policies = append(policies, services.ShadowPolicy{
+				Matches:    p.Match.Matches,
+				Model:      p.Model,
+				SampleRate: p.SampleRate,
+			})
+		}
+		store, err := shadow.NewStore(cfg.Shadow.Path)
+		if err != nil {
+			return nil, err
+		}
+		mirror := shadow.NewMirror(store, llmService.ChatCompletion, shadow.Options{
+			MaxInFlight: cfg.Shadow.MaxInFlight,
+			Timeout:     cfg.Shadow.Timeout,
+		}, logger)
+		closers = append(closers, mirror)
+		llmService = services.NewShadowService(llmService, policies, mirror)
+	}
+
+	if auto := cfg.File.AutoModel; auto != nil {
+		if auto.CheapModel == "" || auto.StrongModel == "" {
+			return nil, fmt.Errorf("auto_model needs cheap_model and strong_model")
+		}
+		llmService = services.NewAutoModelService(llmService, services.AutoModelOptions{
+			Model:       cmp.Or(auto.Model, "auto"),
+			CheapModel:  auto.CheapModel,
+			StrongModel: auto.StrongModel,
+			Heuristic: classifier.Heuristic{
+				Threshold:        cmp.Or(auto.Threshold, 3),
+				LongPromptTokens: cmp.Or(auto.LongPromptTokens, 2000),
+			},
+			ClassifierModel:   auto.ClassifierModel,
+			ClassifierTimeout: time.Duration(cmp.Or(auto.ClassifierTimeoutMS, 3000)) * time.Millisecond,
+		}, logger)
+	}
+
+	if len(cfg.File.Ensembles) > 0 {
+		var ensembles []services.Ensemble
+		for _, e := range cfg.File.Ensembles {
+			if e.Model == "" || len(e.Candidates) < 2 {
+				return nil, fmt.Errorf("ensemble needs a model and at least two candidates")
+			}
+			ensembles = append(ensembles, services.Ensemble{
+				Model:             e.Model,
+				Candidates:        e.Candidates,
+				JudgeModel:        e.JudgeModel,
+				IncludeCandidates: e.IncludeCandidates,
+				Timeout:           time.Duration(e.TimeoutMS) * time.Millisecond,
+			})
+		}
+		llmService = services.NewEnsembleService(llmService, ensembles, logger)
+	}
+
+	if cfg.CoalesceRequests {
+		llmService = services.NewCoalescingService(llmService)
+	}
+
+	if cfg.SemanticCache.Enabled {
+		embedder := providers.NewOpenAIEmbedder(cfg.APIKeys["openai"], cfg.SemanticCache.EmbeddingModel)
+		llmService = services.NewSemanticCachingService(llmService, embedder, cache.NewVectorIndex(cfg.SemanticCache.Capacity), services.SemanticCacheOptions{
+			TTL:       cfg.SemanticCache.TTL,
+			Mode:      cfg.SemanticCache.Mode,
+			Threshold: float32(cfg.SemanticCache.Threshold),
+		}, logger)
+	}
+
+	if cfg.Cache.Backend != "" {
+		store, err := cache.OpenStore(cfg.Cache.Backend, cfg.Cache.Dir, cfg.Cache.Capacity)
+		if err != nil {
+			return nil, err
+		}
+		llmService = services.NewCachingService(llmService, store, services.CacheOptions{
+			TTL:  cfg.Cache.TTL,
+			Mode: cfg.Cache.Mode,
+		}, logger)
+	}
+
+	// The caches sit inside the PII layer so they only ever hold redacted
+	// text; placeholders in a cached reply are restored per request.
+	if len(cfg.File.PII) > 0 {
+		policies, err := piiPolicies(cfg.File.PII)
+		if err != nil {
+			return nil, err
+		}
+		llmService = services.NewPIIService(llmService, policies, logger)
+	}
+
+	// Experiments pick the model before the caches and coalescing see the
+	// request, so clients on different arms never share an answer.
+	if len(cfg.File.Experiments) > 0 {
+		var exps []*experiments.Experiment
+		for _, e := range cfg.File.Experiments {
+			var arms []experiments.Arm
+			for _, a := range e.Arms {
+				arms = append(arms, experiments.Arm{Name: a.Name, Model: a.Model, Weight: a.Weight})
+			}
+			exp, err := experiments.New(e.ID, e.Match.Matches, e.StickyBy, arms)
+			if err != nil {
+				return nil, err
+			}
+			exps = append(exps, exp)
+		}
+		llmService = services.NewExperimentService(llmService, exps, m.RecordExperiment)
+	}
+
+	if len(cfg.File.Guardrails) > 0 {
+		policies, err := guardrailPolicies(cfg.File.Guardrails)
+		if err != nil {
+			return nil, err
+		}
+		llmService = services.NewGuardrailService(llmService, policies, logger)
+	}
+
+	templateStore, systemPrompts, err := promptPolicies(cfg.File)
 	if err != nil {
 		return nil, err
 	}
+	if len(systemPrompts) > 0 {
+		llmService = services.NewSystemPromptService(llmService, systemPrompts, logger)
+	}
 
-	RegisterRoutes(engine, llmHandler)
+	var sessionHandler *handlers.SessionHandler
+	if cfg.SessionStore != "" {
+		store, err := sessions.OpenStore(cfg.SessionStore, cfg.SessionPath)
+		if err != nil {
+			return nil, err
+		}
+		closers = append(closers, store)
+		llmService = services.NewSessionService(llmService, store, logger)
+		sessionHandler = handlers.NewSessionHandler(store, logger)
+	}
+
+	llmService = services.NewTemplateService(llmService, templateStore, logger)
+
+	if auditLogger != nil {
+		llmService = services.NewAuditService(llmService, auditLogger)
+	}
+
+	var jobHandler *handlers.JobHandler
+	if cfg.Jobs.Enabled {
+		store, err := jobs.NewStore(cfg.Jobs.Path)
+		if err != nil {
+			return nil, err
+		}
+		runner, err := jobs.NewRunner(store, llmService.ChatCompletion, jobs.Options{
+			Workers: cfg.Jobs.Workers,
+			Timeout: cfg.Jobs.Timeout,
+			Webhooks: jobs.WebhookOptions{
+				AllowedHosts: cfg.Jobs.WebhookAllowedHosts,
+				Secret:       cfg.Jobs.WebhookSecret,
+			},
+		}, logger)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		closers = append(closers, runner)
+		jobHandler = handlers.NewJobHandler(runner, logger)
+	}
+
+	var batchHandler *handlers.BatchHandler
+	if cfg.Batches.Enabled {
+		store, err := batches.NewStore(cfg.Batches.Dir)
+		if err != nil {
+			return nil, err
+		}
+		runner, err := batches.NewRunner(store, llmService.ChatCompletion, batchStatusCode, cfg.Batches.Concurrency, logger)
+		if err != nil {
+			store.Close()
+			return nil, err
+		}
+		closers = append(closers, runner)
+		batchHandler = handlers.NewBatchHandler(store, runner, int64(cfg.Batches.MaxFileMB)<<20, logger)
+	}
+
+	llmHandler, err := handlers.NewLLMHandler(llmService, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	RegisterRoutes(engine, llmHandler, sessionHandler, jobHandler, batchHandler, handlers.NewAdminHandler(router), cfg.AdminAPIKey, m.Handler())
 	return &Server{
-		engine: engine,
-		cfg:    cfg,
+		engine:          engine,
+		cfg:             cfg,
+		logger:          logger,
+		shutdownTracing: shutdownTracing,
+		closers:         closers,
 	}, nil
 }
 
+func keyPool(name string, pool config.KeyPool, m *metrics.Metrics, logger *slog.Logger, cachePolicy providers.PromptCachePolicy) (providers.Provider, error) {
+	if len(pool.Members) == 0 {
+		return nil, fmt.Errorf("key pool %s has no members", name)
+	}
+	var members []providers.PoolMember
+	for i, member := range pool.Members {
+		memberName := member.Name
+		if memberName == "" {
+			memberName = fmt.Sprintf("%s-%d", name, i)
+		}
+		key := member.Key()
+		if key == "" {
+			return nil, fmt.Errorf("key pool %s: member %s has no API key", name, memberName)
+		}
+		provider, err := providers.NewEndpointProvider(name, key, member.BaseURL, logger, cachePolicy)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, providers.PoolMember{
+			Name:        memberName,
+			Weight:      member.Weight,
+			MaxInFlight: member.MaxInFlight,
+			Provider:    provider,
+		})
+	}
+
+	cooldown := 30 * time.Second
+	if pool.CooldownSeconds > 0 {
+		cooldown = time.Duration(pool.CooldownSeconds) * time.Second
+	}
+	return providers.NewPool(name, members, providers.PoolOptions{
+		Cooldown:         cooldown,
+		FailureThreshold: cmp.Or(pool.FailureThreshold, 3),
+		OnStateChange: func(provider, member string, state providers.MemberState) {
+			m.SetCircuitState(provider, member, circuitStates[state])
+		},
+	}, logger), nil
+}
+
+var circuitStates = map[providers.MemberState]metrics.CircuitState{
+	providers.MemberHealthy:     metrics.CircuitClosed,
+	providers.MemberProbing:     metrics.CircuitHalfOpen,
+	providers.MemberCoolingDown: metrics.CircuitOpen,
+}
+
+func newRouter(rc config.RoutingConfig) (*routing.Router, error) {
+	var routes []routing.Route
+	for _, r := range rc.Routes {
+		routes = append(routes, routing.Route{
+			Alias:   r.Alias,
+			Targets: r.Targets,
+			Objective: routing.Objective{
+				Optimize:     routing.Optimize(r.Optimize),
+				MaxP95:       time.Duration(r.MaxP95MS) * time.Millisecond,
+				MaxErrorRate: r.MaxErrorRate,
+			},
+		})
+	}
+	exploreRate := 0.05
+	if rc.ExploreRate != nil {
+		exploreRate = *rc.ExploreRate
+	}
+	return routing.NewRouter(routes, routing.Options{
+		Window:      time.Duration(cmp.Or(rc.WindowSeconds, 300)) * time.Second,
+		MinSamples:  cmp.Or(rc.MinSamples, 20),
+		ExploreRate: exploreRate,
+	})
+}
+
+func piiPolicies(policies []config.PIIPolicy) ([]services.PIIPolicy, error) {
+	var out []services.PIIPolicy
+	for _, policy := range policies {
+		custom := make(map[string]string, len(policy.Custom))
+		for _, c := range policy.Custom {
+			custom[c.Name] = c.Pattern
+		}
+		detector, err := pii.NewDetector(policy.Entities, custom)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, services.PIIPolicy{
+			Matches:  policy.Match.Matches,
+			Detector: detector,
+		})
+	}
+	return out, nil
+}
+
+func guardrailPolicies(policies []config.GuardrailPolicy) ([]services.GuardrailPolicy, error) {
+	var out []services.GuardrailPolicy
+	for _, policy := range policies {
+		var rails []guardrails.Guardrail
+		for _, rule := range policy.Rules {
+			rail, err := newGuardrail(rule)
+			if err != nil {
+				return nil, err
+			}
+			rails = append(rails, rail)
+		}
+		out = append(out, services.GuardrailPolicy{
+			Matches:    policy.Match.Matches,
+			Guardrails: rails,
+		})
+	}
+	return out, nil
+}
+
+func newGuardrail(rule config.GuardrailRule) (guardrails.Guardrail, error) {
+	action := guardrails.ActionBlock
+	if rule.Action != "" {
+		var ok bool
+		if action, ok = guardrails.ParseAction(rule.Action); !ok {
+			return nil, fmt.Errorf("unknown guardrail action: %s", rule.Action)
+		}
+	}
+	name := rule.Name
+	if name == "" {
+		name = rule.Type
+	}
+
+	switch rule.Type {
+	case "prompt_injection":
+		return guardrails.NewPromptInjection(action), nil
+	case "blocklist", "banned_topic":
+		var stages []guardrails.Stage
+		for _, stage := range rule.Stages {
+			stages = append(stages, guardrails.Stage(stage))
+		}
+		return guardrails.NewBlocklist(name, rule.Terms, action, stages)
+	case "max_output_length":
+		if rule.MaxChars <= 0 {
+			return nil, fmt.Errorf("guardrail %s: max_chars must be positive", name)
+		}
+		return guardrails.NewMaxLength(rule.MaxChars, action), nil
+	case "hook":
+		if rule.URL == "" {
+			return nil, fmt.Errorf("guardrail %s: url is required", name)
+		}
+		timeout := 2 * time.Second
+		if rule.TimeoutMS > 0 {
+			timeout = time.Duration(rule.TimeoutMS) * time.Millisecond
+		}
+		return guardrails.NewHook(name, rule.URL, timeout, rule.FailClosed), nil
+	}
+	return nil, fmt.Errorf("unknown guardrail type: %s", rule.Type)
+}
+
+func promptPolicies(fc config.FileConfig) (*prompts.Store, []services.SystemPromptPolicy, error) {
+	var store *prompts.Store
+	if len(fc.Templates) > 0 {
+		var templates []prompts.Template
+		for _, t := range fc.Templates {
+			template := prompts.Template{ID: t.ID, Version: t.Version}
+			for _, m := range t.Messages {
+				template.Messages = append(template.Messages, models.Message{Role: m.Role, Content: m.Content})
+			}
+			templates = append(templates, template)
+		}
+		var err error
+		if store, err = prompts.NewStore(templates); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var policies []services.SystemPromptPolicy
+	for i, p := range fc.SystemPrompts {
+		if p.Mode != services.SystemPromptPrepend && p.Mode != services.SystemPromptEnforce {
+			return nil, nil, fmt.Errorf("unknown system prompt mode: %s", p.Mode)
+		}
+		content, err := prompts.Parse(fmt.Sprintf("system_prompts[%d]", i), p.Content)
+		if err != nil {
+			return nil, nil, err
+		}
+		policies = append(policies, services.SystemPromptPolicy{
+			Matches: p.Match.Matches,
+			Mode:    p.Mode,
+			Content: content,
+		})
+	}
+	return store, policies, nil
+}
+
+// batchStatusCode reports the status a batched request would have received
+// from /v1/chat/completions.
+func batchStatusCode(err error) int {
+	var reqErr *services.RequestError
+	if errors.As(err, &reqErr) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 func (s *Server) Run() {
 	port := s.cfg.Port
 	addr := ":" + port
@@ -54,6 +551,7 @@ func (s *Server) Run() {
 		Addr:    addr,
 		Handler: s.engine,
 	}
+	s.logger.Info("server listening", slog.String("addr", addr))
 	go func() {
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			panic("Failed to start server: " + err.Error())
@@ -73,4 +571,16 @@ func (s *Server) Shutdown() {
 			panic("Failed to shutdown server: " + err.Error())
 		}
 	}
+
+	// Close in reverse order so nothing is closed before the components
+	// still using it, e.g. the audit log before the job workers.
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		if err := s.closers[i].Close(); err != nil {
+			s.logger.Error("failed to close", slog.Any("error", err))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.shutdownTracing(ctx)
 }
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/llm-router/internal/audit"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+type auditService struct {
+	next   LLMService
+	logger *audit.Logger
+}
+
+// NewAuditService wraps next so every reply a client receives is audited
+// with where it came from: upstream, a cache, a coalesced call or a
+// guardrail. It belongs outside every other layer.
+func NewAuditService(next LLMService, logger *audit.Logger) LLMService {
+	return &auditService{
+		next:   next,
+		logger: logger,
+	}
+}
+
+func (s *auditService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	ctx = s.begin(ctx)
+	start := time.Now()
+	resp, err := s.next.ChatCompletion(ctx, req)
+	s.logger.Finish(ctx, audit.Reply{
+		Source:   replySource(ctx, resp),
+		Request:  req,
+		Response: resp,
+		Latency:  time.Since(start),
+	})
+	return resp, err
+}
+
+func (s *auditService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	ctx = s.begin(ctx)
+	start := time.Now()
+	acc := models.NewChunkAccumulator()
+	received := false
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, req)
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			received = true
+			acc.Add(chunk)
+			return chunk
+		},
+		OnDone: func() {
+			var resp *models.ChatCompletionResponse
+			if received {
+				resp = acc.Response()
+			}
+			s.logger.Finish(ctx, audit.Reply{
+				Source:   replySource(ctx, resp),
+				Stream:   true,
+				Request:  req,
+				Response: resp,
+				Latency:  time.Since(start),
+			})
+		},
+	})
+}
+
+// begin starts the request's entry. The layers below report how they served
+// it through response headers, so requests without an HTTP reply, such as
+// jobs and batches, get headers of their own.
+func (s *auditService) begin(ctx context.Context) context.Context {
+	if meta, ok := requestmeta.Lookup(ctx); ok && meta.ResponseHeader == nil {
+		meta.ResponseHeader = make(http.Header)
+		ctx = requestmeta.WithMeta(ctx, meta)
+	}
+	return s.logger.Begin(ctx)
+}
+
+// replySource tells where a reply came from.
+func replySource(ctx context.Context, resp *models.ChatCompletionResponse) string {
+	if resp != nil {
+		for _, choice := range resp.Choices {
+			if choice.FinishReason == models.FinishReasonContentFilter {
+				return audit.SourceFiltered
+			}
+		}
+	}
+	header := requestmeta.FromContext(ctx).ResponseHeader
+	switch {
+	case header.Get(CacheHeader) == "HIT":
+		return audit.SourceCache
+	case header.Get(SemanticCacheHeader) == "HIT":
+		return audit.SourceSemanticCache
+	case header.Get(CoalescedHeader) != "":
+		return audit.SourceCoalesced
+	default:
+		return audit.SourceUpstream
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/accounting"
+	"github.com/llm-router/internal/audit"
+	"github.com/llm-router/internal/cache"
+	"github.com/llm-router/internal/models"
+)
+
+// memorySink keeps the entries written to it.
+type memorySink struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (s *memorySink) Write(entries []audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *memorySink) Close() error { return nil }
+
+func TestAuditRecordsEveryReplyOnce(t *testing.T) {
+	sink := &memorySink{}
+	logger := audit.NewLogger(sink, audit.Options{SampleRate: 1, Redact: audit.RedactNone}, discardLogger())
+
+	// Like the base service, the upstream records each call it makes.
+	upstream := &fakeLLM{complete: func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+		resp := textResponse(req.Model, "hello")
+		logger.Record(ctx, accounting.Record{Provider: "openai", Model: req.Model, Usage: models.Usage{TotalTokens: 10}, Cost: 0.5, Time: time.Now()})
+		return resp, nil
+	}}
+	svc := NewAuditService(NewCachingService(upstream, cache.NewLRU(10), CacheOptions{TTL: time.Minute, Mode: CacheModeAll}, nil), logger)
+
+	for range 2 {
+		ctx, _ := requestContext(context.Background(), "key-a")
+		if _, err := svc.ChatCompletion(ctx, newRequest("gpt-4o", "hi")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, _ := requestContext(context.Background(), "key-a")
+	if _, err := collect(svc.ChatCompletionStream(ctx, &models.ChatCompletionRequest{Model: "gpt-4o", Messages: newRequest("", "hi").Messages, Stream: true})); err != nil {
+		t.Fatal(err)
+	}
+	logger.Close()
+
+	want := []struct {
+		source   string
+		provider string
+		cost     float64
+		stream   bool
+	}{
+		{audit.SourceUpstream, "openai", 0.5, false},
+		{audit.SourceCache, "", 0, false},
+		{audit.SourceCache, "", 0, true},
+	}
+	if len(sink.entries) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(sink.entries), len(want), sink.entries)
+	}
+	for i, w := range want {
+		e := sink.entries[i]
+		if e.Source != w.source || e.Provider != w.provider || e.Cost != w.cost || e.Stream != w.stream {
+			t.Errorf("entry %d = %s/%q/%v/%v, want %s/%q/%v/%v", i, e.Source, e.Provider, e.Cost, e.Stream, w.source, w.provider, w.cost, w.stream)
+		}
+		if e.Key != "key-a" || e.Model != "gpt-4o" || len(e.Response) == 0 {
+			t.Errorf("entry %d = %+v", i, e)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/llm-router/internal/classifier"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+const classifierPrompt = "You route requests to language models. Reply with exactly one word: " +
+	"\"complex\" if the user's request needs a strong model (multi-step reasoning, non-trivial code, " +
+	"careful analysis or long structured output), otherwise \"simple\"."
+
+// classifierInputChars bounds how much of the prompt the classifier model
+// sees.
+const classifierInputChars = 4000
+
+// AutoModelOptions configure the virtual model. A ClassifierModel, when
+// set, decides each request and the heuristic is only used if it fails.
+type AutoModelOptions struct {
+	Model             string
+	CheapModel        string
+	StrongModel       string
+	Heuristic         classifier.Heuristic
+	ClassifierModel   string
+	ClassifierTimeout time.Duration
+}
+
+type autoModelService struct {
+	next   LLMService
+	opts   AutoModelOptions
+	logger *slog.Logger
+}
+
+// NewAutoModelService wraps next so requests for the virtual model go to
+// the cheap or the strong model depending on how demanding they look.
+// Every decision is logged with the signals behind it.
+func NewAutoModelService(next LLMService, opts AutoModelOptions, logger *slog.Logger) LLMService {
+	return &autoModelService{
+		next:   next,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+func (s *autoModelService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	if req.Model != s.opts.Model {
+		return s.next.ChatCompletion(ctx, req)
+	}
+	return s.next.ChatCompletion(ctx, s.resolve(ctx, req))
+}
+
+func (s *autoModelService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	if req.Model != s.opts.Model {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+	return s.next.ChatCompletionStream(ctx, s.resolve(ctx, req))
+}
+
+func (s *autoModelService) resolve(ctx context.Context, req *models.ChatCompletionRequest) *models.ChatCompletionRequest {
+	decision := s.opts.Heuristic.Classify(req)
+	if s.opts.ClassifierModel != "" {
+		if tier, err := s.classify(ctx, req); err != nil {
+			s.logger.WarnContext(ctx, "auto model classifier failed, using heuristic", slog.Any("error", err))
+		} else {
+			decision.Tier = tier
+			decision.Source = "model"
+		}
+	}
+
+	resolved := *req
+	resolved.Model = s.opts.CheapModel
+	if decision.Tier == classifier.TierStrong {
+		resolved.Model = s.opts.StrongModel
+	}
+
+	s.logger.InfoContext(ctx, "auto model decision",
+		slog.String("model", resolved.Model),
+		slog.String("tier", string(decision.Tier)),
+		slog.String("source", decision.Source),
+		slog.Int("score", decision.Score),
+		slog.Any("reasons", decision.Reasons),
+	)
+	requestmeta.SetResponseHeader(ctx, RoutedModelHeader, resolved.Model)
+	return &resolved
+}
+
+// classify asks the classifier model for a tier.
+func (s *autoModelService) classify(ctx context.Context, req *models.ChatCompletionRequest) (classifier.Tier, error) {
+	var prompt strings.Builder
+	for _, m := range req.Messages {
+		if m.Role == "user" {
+			prompt.WriteString(m.Content)
+			prompt.WriteString("\n")
+		}
+	}
+	input := prompt.String()
+	if runes := []rune(input); len(runes) > classifierInputChars {
+		input = string(runes[len(runes)-classifierInputChars:])
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.opts.ClassifierTimeout)
+	defer cancel()
+
+	maxTokens := int64(2)
+	temperature := 0.0
+	resp, err := s.next.ChatCompletion(ctx, &models.ChatCompletionRequest{
+		Model: s.opts.ClassifierModel,
+		Messages: []models.Message{
+			{Role: "system", Content: classifierPrompt},
+			{Role: "user", Content: input},
+		},
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) > 0 && strings.Contains(strings.ToLower(resp.Choices[0].Message.Content), "complex") {
+		return classifier.TierStrong, nil
+	}
+	return classifier.TierCheap, nil
+}
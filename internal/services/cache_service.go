@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/llm-router/internal/cache"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+const (
+	// CacheHeader opts a request in ("on") or out ("off") of the response
+	// cache. The reply carries the outcome: HIT, MISS or BYPASS.
+	CacheHeader = "X-Router-Cache"
+	// CacheTTLHeader overrides the cache TTL in seconds for a single request.
+	CacheTTLHeader = "X-Router-Cache-TTL"
+)
+
+// Cache modes deciding which requests are cached without an explicit opt-in.
+const (
+	CacheModeDeterministic = "deterministic"
+	CacheModeAll           = "all"
+)
+
+type CacheOptions struct {
+	TTL  time.Duration
+	Mode string
+}
+
+type cachingService struct {
+	next   LLMService
+	store  cache.Store
+	opts   CacheOptions
+	logger *slog.Logger
+}
+
+// NewCachingService wraps next with an exact-match response cache. Entries
+// are scoped by client key.
+func NewCachingService(next LLMService, store cache.Store, opts CacheOptions, logger *slog.Logger) LLMService {
+	return &cachingService{
+		next:   next,
+		store:  store,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+func (s *cachingService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	if !s.cacheable(ctx, req) {
+		requestmeta.SetResponseHeader(ctx, CacheHeader, "BYPASS")
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	key := cache.Key(requestmeta.FromContext(ctx).Key, req)
+	if cached, ok := s.store.Get(ctx, key); ok {
+		requestmeta.SetResponseHeader(ctx, CacheHeader, "HIT")
+		return copyResponse(cached), nil
+	}
+	requestmeta.SetResponseHeader(ctx, CacheHeader, "MISS")
+
+	resp, err := s.next.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if complete(resp) {
+		s.set(ctx, key, req, copyResponse(resp))
+	}
+	return resp, nil
+}
+
+func (s *cachingService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	if !s.cacheable(ctx, req) {
+		requestmeta.SetResponseHeader(ctx, CacheHeader, "BYPASS")
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	key := cache.Key(requestmeta.FromContext(ctx).Key, req)
+	if cached, ok := s.store.Get(ctx, key); ok {
+		requestmeta.SetResponseHeader(ctx, CacheHeader, "HIT")
+		return replayStream(ctx, cached, req.IncludeUsage())
+	}
+	requestmeta.SetResponseHeader(ctx, CacheHeader, "MISS")
+
+	// Always ask for usage upstream so the cached entry has it, and strip it
+	// again below if this client did not opt in.
+	upstreamReq := *req
+	upstreamReq.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, &upstreamReq)
+
+	acc := models.NewChunkAccumulator()
+	failed := false
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			acc.Add(chunk)
+			return usageForClient(chunk, req)
+		},
+		OnError: func(err error) {
+			failed = true
+		},
+		OnDone: func() {
+			resp := acc.Response()
+			if !failed && complete(resp) {
+				s.set(context.WithoutCancel(ctx), key, req, resp)
+			}
+		},
+	})
+}
+
+func (s *cachingService) cacheable(ctx context.Context, req *models.ChatCompletionRequest) bool {
+	return cacheAllowed(ctx, req, s.opts.Mode)
+}
+
+// cacheAllowed applies the per-request cache headers, falling back to mode
+// when the client expressed no preference.
+func cacheAllowed(ctx context.Context, req *models.ChatCompletionRequest, mode string) bool {
+	header := requestmeta.FromContext(ctx).Header
+	if header != nil {
+		switch strings.ToLower(header.Get(CacheHeader)) {
+		case "on":
+			return true
+		case "off":
+			return false
+		}
+		cacheControl := strings.ToLower(header.Get("Cache-Control"))
+		if strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "no-store") {
+			return false
+		}
+	}
+
+	if mode == CacheModeAll {
+		return true
+	}
+	return req.Temperature != nil && *req.Temperature == 0
+}
+
+func (s *cachingService) set(ctx context.Context, key string, req *models.ChatCompletionRequest, resp *models.ChatCompletionResponse) {
+	ttl := s.opts.TTL
+	if header := requestmeta.FromContext(ctx).Header; header != nil {
+		if seconds, err := strconv.Atoi(header.Get(CacheTTLHeader)); err == nil && seconds > 0 {
+			ttl = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if err := s.store.Set(ctx, key, resp, ttl); err != nil {
+		s.logger.WarnContext(ctx, "failed to store cached response",
+			slog.String("model", req.Model),
+			slog.Any("error", err),
+		)
+	}
+}
+
+// complete reports whether every choice finished on its own, so truncated,
+// filtered or failed completions are never cached.
+func complete(resp *models.ChatCompletionResponse) bool {
+	if !finished(resp) {
+		return false
+	}
+	for _, choice := range resp.Choices {
+		if choice.FinishReason == "length" || choice.FinishReason == models.FinishReasonContentFilter {
+			return false
+		}
+	}
+	return true
+}
+
+// finished reports whether every choice has a finish reason, which a
+// stream that broke off never sends.
+func finished(resp *models.ChatCompletionResponse) bool {
+	if len(resp.Choices) == 0 {
+		return false
+	}
+	for _, choice := range resp.Choices {
+		if choice.FinishReason == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func copyResponse(resp *models.ChatCompletionResponse) *models.ChatCompletionResponse {
+	c := *resp
+	c.Choices = append([]models.ChatCompletionChoice(nil), resp.Choices...)
+	return &c
+}
+
+// replayStream serves a stored response as if it were streamed upstream.
+func replayStream(ctx context.Context, resp *models.ChatCompletionResponse, includeUsage bool) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	chunkCh := make(chan *models.ChatCompletionChunk)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer close(chunkCh)
+		defer close(errCh)
+
+		for _, chunk := range models.ChunksFromResponse(resp, includeUsage) {
+			select {
+			case chunkCh <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return chunkCh, errCh
+}
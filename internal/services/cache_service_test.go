@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/cache"
+	"github.com/llm-router/internal/models"
+)
+
+// wordStream streams text one chunk per word, ending with reason (none if
+// empty) and a usage chunk when the request asked for one.
+func wordStream(reason string, words ...string) func(context.Context, *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	return func(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+		chunkCh := make(chan *models.ChatCompletionChunk, len(words)+2)
+		errCh := make(chan error, 1)
+		for _, w := range words {
+			chunkCh <- textChunk(req.Model, w)
+		}
+		if reason != "" {
+			chunkCh <- &models.ChatCompletionChunk{Model: req.Model, Choices: []models.ChatCompletionChunkChoice{{FinishReason: &reason}}}
+		}
+		if req.IncludeUsage() {
+			chunkCh <- &models.ChatCompletionChunk{Model: req.Model, Usage: &models.Usage{PromptTokens: 3, CompletionTokens: 2, TotalTokens: 5}}
+		}
+		close(chunkCh)
+		close(errCh)
+		return chunkCh, errCh
+	}
+}
+
+func streamRequest(includeUsage bool) *models.ChatCompletionRequest {
+	req := newRequest("gpt-4o", "hi")
+	req.Stream = true
+	if includeUsage {
+		req.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+	}
+	return req
+}
+
+func TestCacheReplaysStreams(t *testing.T) {
+	tests := []struct {
+		name         string
+		includeUsage bool
+	}{
+		{"without usage", false},
+		{"with usage", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeLLM{stream: wordStream("stop", "hello", " there")}
+			svc := NewCachingService(next, cache.NewLRU(10), CacheOptions{TTL: time.Minute, Mode: CacheModeAll}, nil)
+
+			for _, want := range []string{"MISS", "HIT"} {
+				ctx, header := requestContext(context.Background(), "key-a")
+				var usage *models.Usage
+				text := ""
+				chunkCh, errCh := svc.ChatCompletionStream(ctx, streamRequest(tt.includeUsage))
+				for chunk := range chunkCh {
+					if chunk.Usage != nil {
+						usage = chunk.Usage
+					}
+					for _, choice := range chunk.Choices {
+						text += choice.Delta.Content
+					}
+				}
+				if err := <-errCh; err != nil || text != "hello there" {
+					t.Fatalf("%s: got %q, %v", want, text, err)
+				}
+				if got := header.Get(CacheHeader); got != want {
+					t.Errorf("cache header = %s, want %s", got, want)
+				}
+				if (usage != nil) != tt.includeUsage {
+					t.Errorf("%s: usage = %v, want it only when asked for", want, usage)
+				}
+			}
+			if n := next.calls.Load(); n != 1 {
+				t.Errorf("upstream calls = %d, want 1", n)
+			}
+
+			// The streamed answer also serves plain completions.
+			ctx, _ := requestContext(context.Background(), "key-a")
+			resp, err := svc.ChatCompletion(ctx, newRequest("gpt-4o", "hi"))
+			if err != nil || resp.Choices[0].Message.Content != "hello there" || resp.Usage.TotalTokens != 5 {
+				t.Errorf("completion from cached stream = %+v, %v", resp, err)
+			}
+		})
+	}
+}
+
+func TestCacheSkipsUnfinishedStreams(t *testing.T) {
+	next := &fakeLLM{stream: wordStream("", "hello")}
+	svc := NewCachingService(next, cache.NewLRU(10), CacheOptions{TTL: time.Minute, Mode: CacheModeAll}, nil)
+
+	for range 2 {
+		ctx, _ := requestContext(context.Background(), "key-a")
+		if _, err := collect(svc.ChatCompletionStream(ctx, streamRequest(false))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := next.calls.Load(); n != 2 {
+		t.Errorf("upstream calls = %d, want a stream without a finish reason not to be cached", n)
+	}
+}
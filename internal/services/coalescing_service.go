@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+
+	"github.com/llm-router/internal/cache"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// CoalescedHeader is set to "true" on replies that joined a call already in
+// flight instead of making their own.
+const CoalescedHeader = "X-Router-Coalesced"
+
+type coalescingService struct {
+	next LLMService
+
+	mu      sync.Mutex
+	calls   map[string]*flightCall
+	streams map[string]*flightStream
+}
+
+// NewCoalescingService wraps next so identical requests from the same client
+// key that arrive while one is already in flight share its upstream call.
+// The upstream call is only cancelled once every caller has gone away.
+func NewCoalescingService(next LLMService) LLMService {
+	return &coalescingService{
+		next:    next,
+		calls:   make(map[string]*flightCall),
+		streams: make(map[string]*flightStream),
+	}
+}
+
+// flightContext detaches the shared upstream call from the caller that
+// started it. The call gets response headers of its own, since it may
+// outlive that caller's reply; each caller copies them into its own.
+func flightContext(ctx context.Context, header http.Header) (context.Context, context.CancelFunc) {
+	meta, _ := requestmeta.Lookup(ctx)
+	meta.ResponseHeader = header
+	return context.WithCancel(requestmeta.WithMeta(context.WithoutCancel(ctx), meta))
+}
+
+func copyFlightHeader(ctx context.Context, header http.Header) {
+	if meta, ok := requestmeta.Lookup(ctx); ok && meta.ResponseHeader != nil {
+		maps.Copy(meta.ResponseHeader, header)
+	}
+}
+
+type flightCall struct {
+	done    chan struct{}
+	header  http.Header
+	resp    *models.ChatCompletionResponse
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func (s *coalescingService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	if !cacheAllowed(ctx, req, CacheModeAll) {
+		return s.next.ChatCompletion(ctx, req)
+	}
+	key := cache.Key(requestmeta.FromContext(ctx).Key, req)
+
+	s.mu.Lock()
+	call, joined := s.calls[key]
+	if joined {
+		call.waiters++
+	} else {
+		header := make(http.Header)
+		flightCtx, cancel := flightContext(ctx, header)
+		call = &flightCall{done: make(chan struct{}), header: header, waiters: 1, cancel: cancel}
+		s.calls[key] = call
+
+		go func() {
+			defer cancel()
+			call.resp, call.err = s.next.ChatCompletion(flightCtx, req)
+
+			s.mu.Lock()
+			if s.calls[key] == call {
+				delete(s.calls, key)
+			}
+			s.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		copyFlightHeader(ctx, call.header)
+		if joined {
+			requestmeta.SetResponseHeader(ctx, CoalescedHeader, "true")
+		}
+		if call.err != nil {
+			return nil, call.err
+		}
+		return copyResponse(call.resp), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			if s.calls[key] == call {
+				delete(s.calls, key)
+			}
+		}
+		s.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// flightStream buffers every chunk of a shared upstream stream so callers
+// that join late are first sent what they missed. header is the stream's
+// own response header map; snapshot is a copy taken once the first chunk
+// or the end of the stream arrives, after which inner layers no longer
+// set headers.
+type flightStream struct {
+	key         string
+	header      http.Header
+	mu          sync.Mutex
+	snapshot    http.Header
+	chunks      []*models.ChatCompletionChunk
+	err         error
+	done        bool
+	updated     chan struct{}
+	subscribers int
+	cancel      context.CancelFunc
+}
+
+func (s *coalescingService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	if !cacheAllowed(ctx, req, CacheModeAll) {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+	key := cache.Key(requestmeta.FromContext(ctx).Key, req)
+
+	s.mu.Lock()
+	flight, joined := s.streams[key]
+	if joined {
+		flight.mu.Lock()
+		flight.subscribers++
+		flight.mu.Unlock()
+	} else {
+		flight = s.startStream(ctx, key, req)
+		s.streams[key] = flight
+	}
+	s.mu.Unlock()
+
+	return s.subscribe(ctx, flight, req, joined)
+}
+
+func (s *coalescingService) startStream(ctx context.Context, key string, req *models.ChatCompletionRequest) *flightStream {
+	header := make(http.Header)
+	flightCtx, cancel := flightContext(ctx, header)
+	flight := &flightStream{
+		key:         key,
+		header:      header,
+		updated:     make(chan struct{}),
+		subscribers: 1,
+		cancel:      cancel,
+	}
+
+	// Usage is always requested upstream and stripped per subscriber, since
+	// stream_options is not part of the coalescing key.
+	upstreamReq := *req
+	upstreamReq.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+
+	go func() {
+		defer cancel()
+
+		chunkCh, errCh := s.next.ChatCompletionStream(flightCtx, &upstreamReq)
+		out, outErr := providers.Relay(flightCtx, chunkCh, errCh, providers.StreamHooks{})
+		for chunk := range out {
+			flight.mu.Lock()
+			if flight.snapshot == nil {
+				flight.snapshot = flight.header.Clone()
+			}
+			flight.chunks = append(flight.chunks, chunk)
+			flight.broadcast()
+			flight.mu.Unlock()
+		}
+		err := <-outErr
+
+		s.mu.Lock()
+		if s.streams[key] == flight {
+			delete(s.streams, key)
+		}
+		s.mu.Unlock()
+
+		flight.mu.Lock()
+		if flight.snapshot == nil {
+			flight.snapshot = flight.header.Clone()
+		}
+		flight.err = err
+		flight.done = true
+		flight.broadcast()
+		flight.mu.Unlock()
+	}()
+
+	return flight
+}
+
+// broadcast wakes every subscriber waiting for news. Callers hold f.mu.
+func (f *flightStream) broadcast() {
+	close(f.updated)
+	f.updated = make(chan struct{})
+}
+
+func (s *coalescingService) subscribe(ctx context.Context, flight *flightStream, req *models.ChatCompletionRequest, joined bool) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	chunkCh := make(chan *models.ChatCompletionChunk)
+	errCh := make(chan error, 1)
+
+	// setHeader copies the flight's headers to this caller's reply before
+	// the handler sees its first chunk or error.
+	headerSet := false
+	setHeader := func(snapshot http.Header) {
+		if headerSet {
+			return
+		}
+		headerSet = true
+		copyFlightHeader(ctx, snapshot)
+		if joined {
+			requestmeta.SetResponseHeader(ctx, CoalescedHeader, "true")
+		}
+	}
+
+	go func() {
+		defer close(chunkCh)
+		defer close(errCh)
+
+		for next := 0; ; {
+			flight.mu.Lock()
+			if next < len(flight.chunks) {
+				chunk := flight.chunks[next]
+				snapshot := flight.snapshot
+				flight.mu.Unlock()
+				next++
+
+				chunk = usageForClient(chunk, req)
+				if chunk == nil {
+					continue
+				}
+				setHeader(snapshot)
+				select {
+				case chunkCh <- chunk:
+					continue
+				case <-ctx.Done():
+				}
+				s.unsubscribe(flight)
+				return
+			}
+			if flight.done {
+				err := flight.err
+				snapshot := flight.snapshot
+				flight.mu.Unlock()
+				setHeader(snapshot)
+				if err != nil {
+					errCh <- err
+				}
+				return
+			}
+			updated := flight.updated
+			flight.mu.Unlock()
+
+			select {
+			case <-updated:
+			case <-ctx.Done():
+				s.unsubscribe(flight)
+				return
+			}
+		}
+	}()
+
+	return chunkCh, errCh
+}
+
+// unsubscribe cancels the upstream stream once its last subscriber has left
+// and unlists it so no new caller can join a cancelled flight.
+func (s *coalescingService) unsubscribe(flight *flightStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flight.mu.Lock()
+	defer flight.mu.Unlock()
+
+	flight.subscribers--
+	if flight.subscribers == 0 && !flight.done {
+		flight.cancel()
+		if s.streams[flight.key] == flight {
+			delete(s.streams, flight.key)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+func TestCoalescingSharesOneCall(t *testing.T) {
+	release := make(chan struct{})
+	next := &fakeLLM{complete: func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+		requestmeta.SetResponseHeader(ctx, RoutedModelHeader, "gpt-4o")
+		<-release
+		return textResponse(req.Model, "shared"), nil
+	}}
+	svc := NewCoalescingService(next).(*coalescingService)
+
+	const callers = 5
+	headers := make([]http.Header, callers)
+	var wg sync.WaitGroup
+	for i := range callers {
+		ctx, header := requestContext(context.Background(), "key-a")
+		headers[i] = header
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := svc.ChatCompletion(ctx, newRequest("gpt-4o", "hello"))
+			if err != nil || resp.Choices[0].Message.Content != "shared" {
+				t.Errorf("caller %d got %v, %v", i, resp, err)
+			}
+		}()
+	}
+	waitFor(t, "callers to join", func() bool {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		for _, call := range svc.calls {
+			return call.waiters == callers
+		}
+		return false
+	})
+	close(release)
+	wg.Wait()
+
+	if n := next.calls.Load(); n != 1 {
+		t.Fatalf("upstream calls = %d, want 1", n)
+	}
+	coalesced := 0
+	for i, header := range headers {
+		if got := header.Get(RoutedModelHeader); got != "gpt-4o" {
+			t.Errorf("caller %d %s = %q, want gpt-4o", i, RoutedModelHeader, got)
+		}
+		if header.Get(CoalescedHeader) == "true" {
+			coalesced++
+		}
+	}
+	if coalesced != callers-1 {
+		t.Errorf("coalesced replies = %d, want %d", coalesced, callers-1)
+	}
+}
+
+func TestCoalescingFirstCallerLeaves(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	next := &fakeLLM{complete: func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+		close(started)
+		<-release
+		// Written after the first caller has gone and its handler has
+		// written its own reply headers.
+		requestmeta.SetResponseHeader(ctx, RoutedModelHeader, "gpt-4o")
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return textResponse(req.Model, "shared"), nil
+	}}
+	svc := NewCoalescingService(next).(*coalescingService)
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstCtx, firstHeader := requestContext(firstCtx, "key-a")
+	firstDone := make(chan error)
+	go func() {
+		_, err := svc.ChatCompletion(firstCtx, newRequest("gpt-4o", "hello"))
+		firstDone <- err
+	}()
+	<-started
+
+	joinerCtx, joinerHeader := requestContext(context.Background(), "key-a")
+	joinerDone := make(chan error)
+	go func() {
+		_, err := svc.ChatCompletion(joinerCtx, newRequest("gpt-4o", "hello"))
+		joinerDone <- err
+	}()
+	waitFor(t, "joiner", func() bool {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		for _, call := range svc.calls {
+			return call.waiters == 2
+		}
+		return false
+	})
+
+	cancelFirst()
+	if err := <-firstDone; !errors.Is(err, context.Canceled) {
+		t.Fatalf("first caller error = %v, want context.Canceled", err)
+	}
+	firstHeader.Set("Content-Type", "application/json")
+	close(release)
+
+	if err := <-joinerDone; err != nil {
+		t.Fatalf("joiner error = %v", err)
+	}
+	if got := joinerHeader.Get(RoutedModelHeader); got != "gpt-4o" {
+		t.Errorf("joiner %s = %q, want gpt-4o", RoutedModelHeader, got)
+	}
+	if got := firstHeader.Get(RoutedModelHeader); got != "" {
+		t.Errorf("first caller got %s %q after leaving", RoutedModelHeader, got)
+	}
+}
+
+func TestCoalescingCancelsWhenEveryCallerLeaves(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	next := &fakeLLM{complete: func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	}}
+	svc := NewCoalescingService(next)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, _ = requestContext(ctx, "key-a")
+	done := make(chan struct{})
+	go func() {
+		svc.ChatCompletion(ctx, newRequest("gpt-4o", "hello"))
+		close(done)
+	}()
+	<-started
+	cancel()
+	<-done
+	<-cancelled
+}
+
+func TestCoalescingStreamJoinerLeaves(t *testing.T) {
+	words := []string{"one ", "two ", "three"}
+	step := make(chan struct{})
+	upstreamDone := make(chan error, 1)
+	next := &fakeLLM{stream: func(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+		requestmeta.SetResponseHeader(ctx, RoutedModelHeader, "gpt-4o")
+		chunkCh := make(chan *models.ChatCompletionChunk)
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(chunkCh)
+			defer close(errCh)
+			for _, w := range words {
+				select {
+				case <-step:
+				case <-ctx.Done():
+					upstreamDone <- ctx.Err()
+					return
+				}
+				chunkCh <- textChunk(req.Model, w)
+			}
+			upstreamDone <- nil
+		}()
+		return chunkCh, errCh
+	}}
+	svc := NewCoalescingService(next).(*coalescingService)
+
+	firstCtx, firstHeader := requestContext(context.Background(), "key-a")
+	firstCh, firstErrCh := svc.ChatCompletionStream(firstCtx, newRequest("gpt-4o", "hello"))
+	step <- struct{}{}
+	first := (<-firstCh).Choices[0].Delta.Content
+
+	leaverCtx, leave := context.WithCancel(context.Background())
+	leaverCtx, leaverHeader := requestContext(leaverCtx, "key-a")
+	leaverCh, _ := svc.ChatCompletionStream(leaverCtx, newRequest("gpt-4o", "hello"))
+	if got := (<-leaverCh).Choices[0].Delta.Content; got != "one " {
+		t.Fatalf("joiner replayed %q, want %q", got, "one ")
+	}
+	if leaverHeader.Get(CoalescedHeader) != "true" || leaverHeader.Get(RoutedModelHeader) != "gpt-4o" {
+		t.Errorf("joiner headers = %v", leaverHeader)
+	}
+	leave()
+	for range leaverCh {
+	}
+	waitFor(t, "joiner to unsubscribe", func() bool {
+		svc.mu.Lock()
+		defer svc.mu.Unlock()
+		flight := svc.streams[svc.anyStreamKey()]
+		if flight == nil {
+			return false
+		}
+		flight.mu.Lock()
+		defer flight.mu.Unlock()
+		return flight.subscribers == 1
+	})
+
+	go func() {
+		step <- struct{}{}
+		step <- struct{}{}
+	}()
+	rest, err := collect(firstCh, firstErrCh)
+	if err != nil || first+rest != "one two three" {
+		t.Fatalf("first caller got %q, %v", first+rest, err)
+	}
+	if err := <-upstreamDone; err != nil {
+		t.Fatalf("upstream ended with %v after a joiner left", err)
+	}
+	if firstHeader.Get(RoutedModelHeader) != "gpt-4o" {
+		t.Errorf("first caller headers = %v", firstHeader)
+	}
+	if n := next.calls.Load(); n != 1 {
+		t.Fatalf("upstream calls = %d, want 1", n)
+	}
+}
+
+func TestCoalescingStreamCancelsWhenEveryCallerLeaves(t *testing.T) {
+	cancelled := make(chan struct{})
+	next := &fakeLLM{stream: func(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+		chunkCh := make(chan *models.ChatCompletionChunk)
+		errCh := make(chan error, 1)
+		go func() {
+			defer close(chunkCh)
+			defer close(errCh)
+			chunkCh <- textChunk(req.Model, "one ")
+			<-ctx.Done()
+			close(cancelled)
+		}()
+		return chunkCh, errCh
+	}}
+	svc := NewCoalescingService(next)
+
+	var chans []<-chan *models.ChatCompletionChunk
+	var cancels []context.CancelFunc
+	for range 2 {
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx, _ = requestContext(ctx, "key-a")
+		chunkCh, _ := svc.ChatCompletionStream(ctx, newRequest("gpt-4o", "hello"))
+		<-chunkCh
+		chans = append(chans, chunkCh)
+		cancels = append(cancels, cancel)
+	}
+	for i, cancel := range cancels {
+		cancel()
+		for range chans[i] {
+		}
+	}
+	<-cancelled
+}
+
+// anyStreamKey returns the key of a stream in flight. Callers hold s.mu.
+func (s *coalescingService) anyStreamKey() string {
+	for key := range s.streams {
+		return key
+	}
+	return ""
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/llm-router/internal/contextwindow"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/llm-router/internal/tokenizer"
+)
+
+// ContextTrimHeader reports how a request was cut down to fit the model's
+// context window, e.g. "strategy=drop_oldest; removed=4; tokens=9120->7010".
+const ContextTrimHeader = "X-Router-Context-Trimmed"
+
+// summaryMaxTokens caps the summary of trimmed turns.
+const summaryMaxTokens = 512
+
+const summaryInstruction = "Summarize the following conversation so it can replace it as context for continuing the conversation. " +
+	"Keep facts, decisions, names and open questions. Reply with the summary only."
+
+type ContextWindowOptions struct {
+	Strategy contextwindow.Strategy
+	KeepLast int
+	// ReserveTokens is kept free for the completion when the request does
+	// not set max_tokens.
+	ReserveTokens int64
+	// SummaryModel is called through the service the context layer wraps,
+	// which sits right above the providers, so it must name a provider
+	// model rather than a routed alias.
+	SummaryModel string
+	// Limits overrides the built-in context window sizes by model prefix.
+	Limits map[string]int64
+}
+
+type contextWindowService struct {
+	next   LLMService
+	opts   ContextWindowOptions
+	logger *slog.Logger
+}
+
+// NewContextWindowService wraps next so requests that would overflow the
+// model's context window are trimmed with the configured strategy before
+// they are sent. Requests for models with no known window pass through, so
+// it belongs below every layer that resolves aliases to models.
+func NewContextWindowService(next LLMService, opts ContextWindowOptions, logger *slog.Logger) LLMService {
+	return &contextWindowService{
+		next:   next,
+		opts:   opts,
+		logger: logger,
+	}
+}
+
+func (s *contextWindowService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	fitted, err := s.fit(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.ChatCompletion(ctx, fitted)
+}
+
+func (s *contextWindowService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	fitted, err := s.fit(ctx, req)
+	if err != nil {
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(errCh)
+		return nil, errCh
+	}
+	return s.next.ChatCompletionStream(ctx, fitted)
+}
+
+func (s *contextWindowService) fit(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionRequest, error) {
+	limit, ok := contextwindow.Limit(req.Model, s.opts.Limits)
+	if !ok || len(req.Messages) == 0 {
+		return req, nil
+	}
+	reserve := s.opts.ReserveTokens
+	if req.MaxTokens != nil {
+		reserve = *req.MaxTokens
+	}
+	budget := limit - reserve
+	count := tokenizer.ForModel(req.Model).Count
+
+	before := contextwindow.Count(req.Messages, count)
+	if before <= budget {
+		return req, nil
+	}
+
+	kept, removed, err := contextwindow.Trim(req.Messages, budget, s.opts.Strategy, s.opts.KeepLast, count)
+	if err != nil {
+		return nil, &RequestError{Err: fmt.Errorf("model %s: %w", req.Model, err)}
+	}
+
+	summarized := 0
+	if s.opts.Strategy == contextwindow.Summarize && len(removed) > 0 {
+		if withSummary, ok := s.summarize(ctx, kept, removed, budget, count); ok {
+			kept = withSummary
+			summarized = len(removed)
+		}
+	}
+
+	fitted := *req
+	fitted.Messages = kept
+	after := contextwindow.Count(kept, count)
+
+	removedCount := len(req.Messages) - len(kept)
+	if summarized > 0 {
+		// The summary took the place of the removed turns.
+		removedCount++
+	}
+	report := fmt.Sprintf("strategy=%s; removed=%d; tokens=%d->%d", s.opts.Strategy, removedCount, before, after)
+	if summarized > 0 {
+		report += fmt.Sprintf("; summarized=%d", summarized)
+	}
+	requestmeta.SetResponseHeader(ctx, ContextTrimHeader, report)
+	s.logger.InfoContext(ctx, "trimmed request to fit context window",
+		slog.String("model", req.Model),
+		slog.String("strategy", string(s.opts.Strategy)),
+		slog.Int64("limit", limit),
+		slog.Int64("tokens_before", before),
+		slog.Int64("tokens_after", after),
+	)
+	return &fitted, nil
+}
+
+// summarize replaces removed with a summary placed after the leading system
+// messages of kept. If the summary pushes the request over budget, more of
+// the oldest turns are dropped. It reports false if summarizing failed, in
+// which case the caller keeps the plain trimmed messages.
+func (s *contextWindowService) summarize(ctx context.Context, kept, removed []models.Message, budget int64, count contextwindow.Counter) ([]models.Message, bool) {
+	var transcript strings.Builder
+	for _, msg := range removed {
+		fmt.Fprintf(&transcript, "%s: %s\n\n", msg.Role, msg.Content)
+	}
+	maxTokens := int64(summaryMaxTokens)
+	resp, err := s.next.ChatCompletion(ctx, &models.ChatCompletionRequest{
+		Model:     s.opts.SummaryModel,
+		MaxTokens: &maxTokens,
+		Messages: []models.Message{
+			{Role: "system", Content: summaryInstruction},
+			{Role: "user", Content: transcript.String()},
+		},
+	})
+	if err != nil || len(resp.Choices) == 0 {
+		s.logger.WarnContext(ctx, "failed to summarize trimmed turns, dropping them instead",
+			slog.String("summary_model", s.opts.SummaryModel),
+			slog.Any("error", err),
+		)
+		return nil, false
+	}
+
+	summary := models.Message{
+		Role:    "system",
+		Content: "Summary of the earlier conversation:\n" + resp.Choices[0].Message.Content,
+	}
+	leading := 0
+	for leading < len(kept) && kept[leading].Role == "system" {
+		leading++
+	}
+	messages := make([]models.Message, 0, len(kept)+1)
+	messages = append(messages, kept[:leading]...)
+	messages = append(messages, summary)
+	messages = append(messages, kept[leading:]...)
+
+	messages, _, err = contextwindow.Trim(messages, budget, contextwindow.DropOldest, 0, count)
+	if err != nil {
+		return nil, false
+	}
+	return messages, true
+}
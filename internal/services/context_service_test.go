@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/llm-router/internal/contextwindow"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/routing"
+)
+
+func TestContextWindowUsesRoutedTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		kept   int
+	}{
+		{"small-model", 2},
+		{"large-model", 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			router, err := routing.NewRouter([]routing.Route{{Alias: "chat", Targets: []string{tt.target}}}, routing.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sent *models.ChatCompletionRequest
+			upstream := &fakeLLM{complete: func(_ context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+				sent = req
+				return textResponse(req.Model, "ok"), nil
+			}}
+			logger := discardLogger()
+			fitted := NewContextWindowService(upstream, ContextWindowOptions{
+				Strategy: contextwindow.DropOldest,
+				Limits:   map[string]int64{"small-model": 150, "large-model": 100000},
+			}, logger)
+			svc := NewRoutingService(fitted, router, logger)
+
+			req := &models.ChatCompletionRequest{Model: "chat"}
+			for i, role := range []string{"system", "user", "assistant", "user", "assistant"} {
+				req.Messages = append(req.Messages, models.Message{Role: role, Content: strings.Repeat("word ", 100) + string(rune('a'+i))})
+			}
+			req.Messages[4].Content = "short"
+
+			ctx, header := requestContext(context.Background(), "key-a")
+			if _, err := svc.ChatCompletion(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			if sent.Model != tt.target || len(sent.Messages) != tt.kept {
+				t.Errorf("sent %s with %d messages, want %s with %d", sent.Model, len(sent.Messages), tt.target, tt.kept)
+			}
+			if trimmed := header.Get(ContextTrimHeader) != ""; trimmed != (tt.kept < 5) {
+				t.Errorf("trim header = %q", header.Get(ContextTrimHeader))
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/ensemble"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// EnsembleHeader reports how an ensemble answer was picked, "judge" or
+// "consensus".
+const EnsembleHeader = "X-Router-Ensemble"
+
+// Ensemble is a virtual model answered by running every candidate model in
+// parallel. JudgeModel, when set, picks the answer; otherwise, or if the
+// judge fails, the consensus answer is returned. Candidates may repeat a
+// model for best-of-N sampling.
+type Ensemble struct {
+	Model             string
+	Candidates        []string
+	JudgeModel        string
+	IncludeCandidates bool
+	// Timeout bounds the candidates; those still running are cancelled and
+	// left out. Zero means no limit beyond the request's own.
+	Timeout time.Duration
+}
+
+type ensembleService struct {
+	next      LLMService
+	ensembles map[string]Ensemble
+	logger    *slog.Logger
+}
+
+// NewEnsembleService wraps next so requests for an ensemble model fan out
+// to its candidates. The reply's usage covers every candidate and the
+// judge. Streaming requests get the chosen answer replayed as a stream,
+// without the candidates.
+func NewEnsembleService(next LLMService, ensembles []Ensemble, logger *slog.Logger) LLMService {
+	byModel := make(map[string]Ensemble, len(ensembles))
+	for _, e := range ensembles {
+		byModel[e.Model] = e
+	}
+	return &ensembleService{
+		next:      next,
+		ensembles: byModel,
+		logger:    logger,
+	}
+}
+
+func (s *ensembleService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	e, ok := s.ensembles[req.Model]
+	if !ok {
+		return s.next.ChatCompletion(ctx, req)
+	}
+	return s.run(ctx, e, req)
+}
+
+func (s *ensembleService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	e, ok := s.ensembles[req.Model]
+	if !ok {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	resp, err := s.run(ctx, e, req)
+	if err != nil {
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(errCh)
+		return nil, errCh
+	}
+	resp.Ensemble = nil
+	return replayStream(ctx, resp, req.IncludeUsage())
+}
+
+// ensembleCandidate is one candidate's call. Each gets its own response
+// headers so parallel calls cannot write to the reply at once; the chosen
+// one's are copied over.
+type ensembleCandidate struct {
+	model   string
+	header  http.Header
+	resp    *models.ChatCompletionResponse
+	err     error
+	latency time.Duration
+}
+
+func (c *ensembleCandidate) content() string {
+	if c.resp == nil || len(c.resp.Choices) == 0 {
+		return ""
+	}
+	return c.resp.Choices[0].Message.Content
+}
+
+func (s *ensembleService) run(ctx context.Context, e Ensemble, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	candidates := s.fanOut(ctx, e, req)
+
+	var answered []*ensembleCandidate
+	var firstErr error
+	for _, c := range candidates {
+		switch {
+		case c.err != nil:
+			if firstErr == nil {
+				firstErr = c.err
+			}
+		case len(c.resp.Choices) > 0:
+			answered = append(answered, c)
+		}
+	}
+	if len(answered) == 0 {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no ensemble candidate of %s answered", e.Model)
+		}
+		return nil, firstErr
+	}
+
+	answers := make([]string, len(answered))
+	for i, c := range answered {
+		answers[i] = c.content()
+	}
+
+	var usage models.Usage
+	strategy, pick := ensemble.StrategyConsensus, ensemble.Consensus(answers)
+	if e.JudgeModel != "" && len(answered) > 1 {
+		judged, judgeUsage, err := s.judge(ctx, e, req, answers)
+		addUsage(&usage, judgeUsage)
+		if err != nil {
+			s.logger.WarnContext(ctx, "ensemble judge failed, using consensus",
+				slog.String("model", e.Model),
+				slog.Any("error", err),
+			)
+		} else {
+			strategy, pick = ensemble.StrategyJudge, judged
+		}
+	}
+	chosen := answered[pick]
+
+	s.logger.InfoContext(ctx, "ensemble decision",
+		slog.String("model", e.Model),
+		slog.String("chosen", chosen.model),
+		slog.String("strategy", strategy),
+		slog.Int("answered", len(answered)),
+		slog.Int("candidates", len(candidates)),
+	)
+
+	resp := copyResponse(chosen.resp)
+	for _, c := range candidates {
+		if c.resp != nil {
+			addUsage(&usage, &c.resp.Usage)
+		}
+	}
+	resp.Usage = usage
+
+	if e.IncludeCandidates {
+		resp.Ensemble = &models.Ensemble{Strategy: strategy}
+		for _, c := range candidates {
+			if c == chosen {
+				resp.Ensemble.Chosen = len(resp.Ensemble.Candidates)
+			}
+			resp.Ensemble.Candidates = append(resp.Ensemble.Candidates, c.summary())
+		}
+	}
+
+	if meta, ok := requestmeta.Lookup(ctx); ok && meta.ResponseHeader != nil {
+		maps.Copy(meta.ResponseHeader, chosen.header)
+	}
+	requestmeta.SetResponseHeader(ctx, RoutedModelHeader, chosen.model)
+	requestmeta.SetResponseHeader(ctx, EnsembleHeader, strategy)
+	return resp, nil
+}
+
+func (s *ensembleService) fanOut(ctx context.Context, e Ensemble, req *models.ChatCompletionRequest) []*ensembleCandidate {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+
+	candidates := make([]*ensembleCandidate, len(e.Candidates))
+	var wg sync.WaitGroup
+	for i, model := range e.Candidates {
+		c := &ensembleCandidate{model: model, header: make(http.Header)}
+		candidates[i] = c
+
+		candidateReq := *req
+		candidateReq.Model = model
+		candidateReq.Stream = false
+		candidateReq.StreamOptions = nil
+
+		meta, _ := requestmeta.Lookup(ctx)
+		meta.ResponseHeader = c.header
+		candidateCtx := requestmeta.WithMeta(ctx, meta)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			c.resp, c.err = s.next.ChatCompletion(candidateCtx, &candidateReq)
+			c.latency = time.Since(start)
+		}()
+	}
+	wg.Wait()
+	return candidates
+}
+
+// judge asks the judge model which answer is best and reports what the
+// call used, even when its verdict is unusable.
+func (s *ensembleService) judge(ctx context.Context, e Ensemble, req *models.ChatCompletionRequest, answers []string) (int, *models.Usage, error) {
+	maxTokens := int64(8)
+	temperature := 0.0
+	meta, _ := requestmeta.Lookup(ctx)
+	meta.ResponseHeader = nil
+	resp, err := s.next.ChatCompletion(requestmeta.WithMeta(ctx, meta), &models.ChatCompletionRequest{
+		Model:       e.JudgeModel,
+		Messages:    ensemble.JudgeMessages(req.Messages, answers),
+		MaxTokens:   &maxTokens,
+		Temperature: &temperature,
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return 0, &resp.Usage, fmt.Errorf("judge returned no choices")
+	}
+	pick, err := ensemble.ParseVerdict(resp.Choices[0].Message.Content, len(answers))
+	return pick, &resp.Usage, err
+}
+
+func (c *ensembleCandidate) summary() models.EnsembleCandidate {
+	summary := models.EnsembleCandidate{Model: c.model, LatencyMS: c.latency.Milliseconds()}
+	if c.err != nil {
+		summary.Error = c.err.Error()
+		return summary
+	}
+	summary.Model = cmp.Or(c.resp.Model, c.model)
+	summary.Usage = &c.resp.Usage
+	if len(c.resp.Choices) > 0 {
+		summary.Content = c.resp.Choices[0].Message.Content
+		summary.FinishReason = c.resp.Choices[0].FinishReason
+	}
+	return summary
+}
+
+func addUsage(total *models.Usage, u *models.Usage) {
+	if u == nil {
+		return
+	}
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	total.TotalTokens += u.TotalTokens
+	total.CacheReadTokens += u.CacheReadTokens
+	total.CacheWriteTokens += u.CacheWriteTokens
+}
@@ -0,0 +1,19 @@
+package services
+
+import "errors"
+
+// RequestError reports a problem with what the client sent rather than a
+// failure in the router or upstream.
+type RequestError struct {
+	Err error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+var errTemplatesDisabled = errors.New("prompt templates are not configured")
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/llm-router/internal/experiments"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// Experiment headers name the experiment a request took part in and the arm
+// it was assigned to.
+const (
+	ExperimentHeader    = "X-Router-Experiment"
+	ExperimentArmHeader = "X-Router-Experiment-Arm"
+)
+
+// ExperimentObserver sees the outcome of every request assigned to an arm.
+type ExperimentObserver func(ctx context.Context, experiment, arm string, latency time.Duration, err error)
+
+type experimentService struct {
+	next        LLMService
+	experiments []*experiments.Experiment
+	observe     ExperimentObserver
+}
+
+// NewExperimentService wraps next so requests matching an experiment are
+// sent to the model of their assigned arm. The first matching experiment
+// applies.
+func NewExperimentService(next LLMService, exps []*experiments.Experiment, observe ExperimentObserver) LLMService {
+	return &experimentService{
+		next:        next,
+		experiments: exps,
+		observe:     observe,
+	}
+}
+
+func (s *experimentService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	exp, arm, assigned := s.assign(ctx, req)
+	if exp == nil {
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := s.next.ChatCompletion(ctx, assigned)
+	s.observe(ctx, exp.ID, arm.Name, time.Since(start), err)
+	return resp, err
+}
+
+func (s *experimentService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	exp, arm, assigned := s.assign(ctx, req)
+	if exp == nil {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	start := time.Now()
+	var streamErr error
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, assigned)
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnError: func(err error) {
+			streamErr = err
+		},
+		OnDone: func() {
+			s.observe(ctx, exp.ID, arm.Name, time.Since(start), streamErr)
+		},
+	})
+}
+
+func (s *experimentService) assign(ctx context.Context, req *models.ChatCompletionRequest) (*experiments.Experiment, experiments.Arm, *models.ChatCompletionRequest) {
+	meta := requestmeta.FromContext(ctx)
+	for _, exp := range s.experiments {
+		if !exp.Matches(meta, req.Model) {
+			continue
+		}
+
+		arm := exp.Assign(stickyUnit(exp, meta, req))
+		requestmeta.SetResponseHeader(ctx, ExperimentHeader, exp.ID)
+		requestmeta.SetResponseHeader(ctx, ExperimentArmHeader, arm.Name)
+
+		assigned := *req
+		assigned.Model = arm.Model
+		return exp, arm, &assigned
+	}
+	return nil, experiments.Arm{}, nil
+}
+
+// stickyUnit is the value an experiment hashes to pick an arm. Anonymous
+// requests without a user fall back to the request ID, which spreads them
+// across arms without stickiness.
+func stickyUnit(exp *experiments.Experiment, meta requestmeta.Meta, req *models.ChatCompletionRequest) string {
+	if exp.StickyBy == experiments.StickyByUser && req.User != "" {
+		return "user:" + req.User
+	}
+	if !meta.Anonymous() {
+		return "key:" + meta.Key
+	}
+	return "request:" + meta.RequestID
+}
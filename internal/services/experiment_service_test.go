@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/cache"
+	"github.com/llm-router/internal/experiments"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+func TestExperimentUsersOnDifferentArmsDoNotShareCache(t *testing.T) {
+	exp, err := experiments.New("exp", func(requestmeta.Meta, string) bool { return true }, experiments.StickyByUser, []experiments.Arm{
+		{Name: "control", Model: "model-a", Weight: 1},
+		{Name: "treatment", Model: "model-b", Weight: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pick two users the experiment puts on different arms.
+	users := map[string]string{}
+	for i := 0; len(users) < 2; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		arm := exp.Assign("user:" + user).Name
+		if _, ok := users[arm]; !ok {
+			users[arm] = user
+		}
+	}
+
+	upstream := &fakeLLM{complete: func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+		return textResponse(req.Model, "answer from "+req.Model), nil
+	}}
+	seen := map[string]int{}
+	svc := NewExperimentService(
+		NewCachingService(upstream, cache.NewLRU(10), CacheOptions{TTL: time.Minute, Mode: CacheModeAll}, nil),
+		[]*experiments.Experiment{exp},
+		func(_ context.Context, _, arm string, _ time.Duration, _ error) { seen[arm]++ },
+	)
+
+	wantModel := map[string]string{"control": "model-a", "treatment": "model-b"}
+	for range 2 {
+		for arm, user := range users {
+			// Both users send the same prompt with the same API key.
+			ctx, header := requestContext(context.Background(), "key-a")
+			req := newRequest("gpt-4o", "hi")
+			req.User = user
+			resp, err := svc.ChatCompletion(ctx, req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "answer from " + wantModel[arm]; resp.Choices[0].Message.Content != want {
+				t.Errorf("%s on %s got %q, want %q", user, arm, resp.Choices[0].Message.Content, want)
+			}
+			if header.Get(ExperimentHeader) != "exp" || header.Get(ExperimentArmHeader) != arm {
+				t.Errorf("%s headers = %v", user, header)
+			}
+		}
+	}
+	if n := upstream.calls.Load(); n != 2 {
+		t.Errorf("upstream calls = %d, want one per arm", n)
+	}
+	if seen["control"] != 2 || seen["treatment"] != 2 {
+		t.Errorf("observed arms = %v, want cache hits counted too", seen)
+	}
+}
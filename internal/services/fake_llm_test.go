@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// fakeLLM is an LLMService whose behaviour each test supplies. It counts the
+// calls it receives.
+type fakeLLM struct {
+	complete func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error)
+	stream   func(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error)
+	calls    atomic.Int64
+}
+
+func (f *fakeLLM) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	f.calls.Add(1)
+	return f.complete(ctx, req)
+}
+
+func (f *fakeLLM) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	f.calls.Add(1)
+	return f.stream(ctx, req)
+}
+
+func newRequest(model, content string) *models.ChatCompletionRequest {
+	return &models.ChatCompletionRequest{
+		Model:    model,
+		Messages: []models.Message{{Role: "user", Content: content}},
+	}
+}
+
+func textResponse(model, text string) *models.ChatCompletionResponse {
+	return &models.ChatCompletionResponse{
+		Model: model,
+		Choices: []models.ChatCompletionChoice{{
+			Message:      models.ChatMessage{Role: "assistant", Content: text},
+			FinishReason: "stop",
+		}},
+	}
+}
+
+func textChunk(model, text string) *models.ChatCompletionChunk {
+	return &models.ChatCompletionChunk{
+		Model:   model,
+		Choices: []models.ChatCompletionChunkChoice{{Delta: models.ChatMessage{Content: text}}},
+	}
+}
+
+// requestContext returns the context of a request from key along with the
+// header map its reply would be written from.
+func requestContext(parent context.Context, key string) (context.Context, http.Header) {
+	header := make(http.Header)
+	return requestmeta.WithMeta(parent, requestmeta.Meta{
+		RequestID:      requestmeta.NewRequestID(),
+		Route:          "/v1/chat/completions",
+		Key:            key,
+		ResponseHeader: header,
+	}), header
+}
+
+// collect reads a stream to the end and returns its text and error.
+func collect(chunkCh <-chan *models.ChatCompletionChunk, errCh <-chan error) (string, error) {
+	var text string
+	for chunk := range chunkCh {
+		for _, choice := range chunk.Choices {
+			text += choice.Delta.Content
+		}
+	}
+	return text, <-errCh
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
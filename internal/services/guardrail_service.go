@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/llm-router/internal/guardrails"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// GuardrailHeader lists the guardrails that acted on a request as
+// "action:rule" pairs, e.g. "flag:prompt_injection".
+const GuardrailHeader = "X-Router-Guardrail"
+
+// streamWindowChars is how much streamed output may go out checked by the
+// local guardrails alone before every guardrail sees it again. All of them
+// also run at the end of each sentence and when a choice finishes.
+const streamWindowChars = 256
+
+// GuardrailPolicy runs Guardrails on requests selected by Matches.
+type GuardrailPolicy struct {
+	Matches    func(meta requestmeta.Meta, model string) bool
+	Guardrails []guardrails.Guardrail
+}
+
+type guardrailService struct {
+	next     LLMService
+	policies []GuardrailPolicy
+	logger   *slog.Logger
+}
+
+// NewGuardrailService wraps next with input and output guardrails. Blocked
+// requests and outputs end with the content_filter finish reason; a blocked
+// stream is cut off at that point and its upstream call cancelled. The first
+// matching policy applies.
+func NewGuardrailService(next LLMService, policies []GuardrailPolicy, logger *slog.Logger) LLMService {
+	return &guardrailService{
+		next:     next,
+		policies: policies,
+		logger:   logger,
+	}
+}
+
+func (s *guardrailService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	policy := s.policyFor(ctx, req)
+	if policy == nil {
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	seen := make(map[string]bool)
+	checked, actions, blocked := s.checkInput(ctx, policy, req, seen)
+	if blocked {
+		setGuardrailHeader(ctx, actions)
+		return filteredResponse(ctx, req), nil
+	}
+
+	resp, err := s.next.ChatCompletion(ctx, checked)
+	if err != nil {
+		return nil, err
+	}
+
+	resp = copyResponse(resp)
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		result := s.evaluate(ctx, policy.Guardrails, guardrails.Content{
+			Stage: guardrails.StageOutput,
+			Role:  choice.Message.Role,
+			Text:  choice.Message.Content,
+			Model: req.Model,
+			Final: true,
+		}, seen)
+		actions = append(actions, guardrailActions(result)...)
+		if result.Blocked != nil {
+			choice.Message.Content = ""
+			choice.FinishReason = models.FinishReasonContentFilter
+			continue
+		}
+		choice.Message.Content = result.Text
+	}
+	setGuardrailHeader(ctx, actions)
+	return resp, nil
+}
+
+// guardedChoice tracks what has been sent to the client for one choice and
+// how much of it every guardrail has seen.
+type guardedChoice struct {
+	text    string
+	checked int
+}
+
+func (s *guardrailService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	policy := s.policyFor(ctx, req)
+	if policy == nil {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	seen := make(map[string]bool)
+	checked, actions, blocked := s.checkInput(ctx, policy, req, seen)
+	setGuardrailHeader(ctx, actions)
+	if blocked {
+		return replayStream(ctx, filteredResponse(ctx, req), req.IncludeUsage())
+	}
+
+	upstreamCtx, cancel := context.WithCancel(ctx)
+	chunkCh, errCh := s.next.ChatCompletionStream(upstreamCtx, checked)
+
+	local := guardrails.Local(policy.Guardrails)
+	choices := make(map[int]*guardedChoice)
+	terminated := false
+
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			if terminated {
+				// Usage still arrives if upstream finished before it saw the
+				// cancellation.
+				if len(chunk.Choices) == 0 {
+					return chunk
+				}
+				return nil
+			}
+
+			c := *chunk
+			c.Choices = append([]models.ChatCompletionChunkChoice(nil), chunk.Choices...)
+			for i := range c.Choices {
+				choice := &c.Choices[i]
+				final := choice.FinishReason != nil
+				if choice.Delta.Content == "" && !final {
+					continue
+				}
+
+				state, ok := choices[choice.Index]
+				if !ok {
+					state = &guardedChoice{}
+					choices[choice.Index] = state
+				}
+
+				// Re-running every guardrail over the whole output on each
+				// chunk is quadratic, so between windows only the local
+				// ones check it.
+				candidate := state.text + choice.Delta.Content
+				full := final || len(candidate)-state.checked >= streamWindowChars || strings.ContainsAny(choice.Delta.Content, ".!?\n")
+				rails := local
+				if full {
+					rails = policy.Guardrails
+				}
+				result := s.evaluate(ctx, rails, guardrails.Content{
+					Stage: guardrails.StageOutput,
+					Role:  "assistant",
+					Text:  candidate,
+					Model: req.Model,
+					Final: final,
+				}, seen)
+
+				// Text already sent cannot be taken back, so a rewrite that
+				// changes it ends the stream like a block.
+				if result.Blocked == nil && !strings.HasPrefix(result.Text, state.text) {
+					result.Blocked = &guardrails.Verdict{Action: guardrails.ActionBlock, Rule: rewriteRule(result), Reason: "rewrote output already sent"}
+					s.logVerdict(ctx, guardrails.StageOutput, *result.Blocked)
+				}
+				if result.Blocked != nil {
+					terminated = true
+					cancel()
+					reason := models.FinishReasonContentFilter
+					choice.Delta.Content = ""
+					choice.FinishReason = &reason
+					c.Choices = []models.ChatCompletionChunkChoice{*choice}
+					return &c
+				}
+
+				choice.Delta.Content = result.Text[len(state.text):]
+				state.text = result.Text
+				if full {
+					state.checked = len(state.text)
+				}
+			}
+			return &c
+		},
+		DropError: func(err error) bool {
+			return terminated && ctx.Err() == nil
+		},
+		OnDone: cancel,
+	})
+}
+
+// checkInput runs input guardrails on every message and returns the request
+// with any rewrites applied.
+func (s *guardrailService) checkInput(ctx context.Context, policy *GuardrailPolicy, req *models.ChatCompletionRequest, seen map[string]bool) (*models.ChatCompletionRequest, []string, bool) {
+	checked := req
+	var actions []string
+	for i, msg := range req.Messages {
+		result := s.evaluate(ctx, policy.Guardrails, guardrails.Content{
+			Stage: guardrails.StageInput,
+			Role:  msg.Role,
+			Text:  msg.Content,
+			Model: req.Model,
+		}, seen)
+		actions = append(actions, guardrailActions(result)...)
+		if result.Blocked != nil {
+			return nil, actions, true
+		}
+		if result.Text != msg.Content {
+			if checked == req {
+				copied := *req
+				copied.Messages = append(req.Messages[:0:0], req.Messages...)
+				checked = &copied
+			}
+			checked.Messages[i].Content = result.Text
+		}
+	}
+	return checked, actions, false
+}
+
+// evaluate runs rails and logs what triggered. Flags are logged once per
+// rule in seen, so a stream re-checked as it grows does not repeat them.
+func (s *guardrailService) evaluate(ctx context.Context, rails []guardrails.Guardrail, content guardrails.Content, seen map[string]bool) guardrails.Result {
+	content.Route = requestmeta.FromContext(ctx).Route
+	result := guardrails.Evaluate(ctx, rails, content, s.logger)
+	for _, v := range result.Flags {
+		if !seen[v.Rule] {
+			seen[v.Rule] = true
+			s.logVerdict(ctx, content.Stage, v)
+		}
+	}
+	if result.Blocked != nil {
+		s.logVerdict(ctx, content.Stage, *result.Blocked)
+	}
+	return result
+}
+
+func (s *guardrailService) logVerdict(ctx context.Context, stage guardrails.Stage, v guardrails.Verdict) {
+	s.logger.WarnContext(ctx, "guardrail triggered",
+		slog.String("stage", string(stage)),
+		slog.String("rule", v.Rule),
+		slog.String("action", string(v.Action)),
+		slog.String("reason", v.Reason),
+	)
+}
+
+func (s *guardrailService) policyFor(ctx context.Context, req *models.ChatCompletionRequest) *GuardrailPolicy {
+	meta := requestmeta.FromContext(ctx)
+	for i := range s.policies {
+		if s.policies[i].Matches(meta, req.Model) {
+			return &s.policies[i]
+		}
+	}
+	return nil
+}
+
+// rewriteRule names the last guardrail that rewrote the result.
+func rewriteRule(result guardrails.Result) string {
+	for i := len(result.Flags) - 1; i >= 0; i-- {
+		if result.Flags[i].Action == guardrails.ActionRewrite {
+			return result.Flags[i].Rule
+		}
+	}
+	return ""
+}
+
+func guardrailActions(result guardrails.Result) []string {
+	var actions []string
+	for _, v := range result.Flags {
+		actions = append(actions, string(v.Action)+":"+v.Rule)
+	}
+	if result.Blocked != nil {
+		actions = append(actions, string(result.Blocked.Action)+":"+result.Blocked.Rule)
+	}
+	return actions
+}
+
+func setGuardrailHeader(ctx context.Context, actions []string) {
+	if len(actions) > 0 {
+		requestmeta.SetResponseHeader(ctx, GuardrailHeader, strings.Join(actions, ", "))
+	}
+}
+
+// filteredResponse answers a blocked request without calling upstream.
+func filteredResponse(ctx context.Context, req *models.ChatCompletionRequest) *models.ChatCompletionResponse {
+	return &models.ChatCompletionResponse{
+		ID:      "chatcmpl-" + requestmeta.FromContext(ctx).RequestID,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   req.Model,
+		Choices: []models.ChatCompletionChoice{
+			{
+				Index:        0,
+				Message:      models.ChatMessage{Role: "assistant"},
+				FinishReason: models.FinishReasonContentFilter,
+			},
+		},
+	}
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/llm-router/internal/guardrails"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// countingGuardrail is a remote-style guardrail that counts its checks and
+// rewrites "secret" once the output contains it.
+type countingGuardrail struct {
+	checks atomic.Int64
+}
+
+func (g *countingGuardrail) Name() string { return "counting" }
+
+func (g *countingGuardrail) Check(_ context.Context, content guardrails.Content) (guardrails.Verdict, error) {
+	g.checks.Add(1)
+	if strings.Contains(content.Text, "secret") {
+		return guardrails.Verdict{Action: guardrails.ActionRewrite, Rewrite: strings.ReplaceAll(content.Text, "secret", "******")}, nil
+	}
+	return guardrails.Verdict{}, nil
+}
+
+func newGuardrailService(next LLMService, logs io.Writer, rails ...guardrails.Guardrail) LLMService {
+	return NewGuardrailService(next, []GuardrailPolicy{{
+		Matches:    func(requestmeta.Meta, string) bool { return true },
+		Guardrails: rails,
+	}}, slog.New(slog.NewTextHandler(logs, nil)))
+}
+
+// chunkStream streams each of words as its own chunk, then stops.
+func chunkStream(words ...string) func(context.Context, *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	return func(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+		chunkCh := make(chan *models.ChatCompletionChunk, len(words)+1)
+		errCh := make(chan error, 1)
+		for _, w := range words {
+			chunkCh <- textChunk(req.Model, w)
+		}
+		stop := "stop"
+		chunkCh <- &models.ChatCompletionChunk{Model: req.Model, Choices: []models.ChatCompletionChunkChoice{{FinishReason: &stop}}}
+		close(chunkCh)
+		close(errCh)
+		return chunkCh, errCh
+	}
+}
+
+func TestGuardrailStreamChecksInWindows(t *testing.T) {
+	tests := []struct {
+		name   string
+		words  []string
+		checks int64
+	}{
+		{"no sentence end", strings.Split(strings.Repeat("w", 100), ""), 1},
+		{"one sentence per chunk", []string{"One.", " Two.", " Three"}, 3},
+		{"long run", strings.Split(strings.Repeat("w", 2*streamWindowChars), ""), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := &countingGuardrail{}
+			blocklist, err := guardrails.NewBlocklist("blocklist", []string{"forbidden"}, guardrails.ActionBlock, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			svc := newGuardrailService(&fakeLLM{stream: chunkStream(tt.words...)}, io.Discard, blocklist, remote)
+
+			ctx, _ := requestContext(context.Background(), "key-a")
+			text, err := collect(svc.ChatCompletionStream(ctx, streamRequest(false)))
+			if err != nil || text != strings.Join(tt.words, "") {
+				t.Fatalf("got %q, %v", text, err)
+			}
+			// Input is checked once; the rest are output windows.
+			if got := remote.checks.Load() - 1; got != tt.checks {
+				t.Errorf("output checks = %d, want %d", got, tt.checks)
+			}
+		})
+	}
+}
+
+func TestGuardrailStreamLocalRulesRunOnEveryChunk(t *testing.T) {
+	blocklist, err := guardrails.NewBlocklist("blocklist", []string{"forbidden"}, guardrails.ActionBlock, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newGuardrailService(&fakeLLM{stream: chunkStream("a ", "forbidden", " word")}, io.Discard, blocklist)
+
+	ctx, _ := requestContext(context.Background(), "key-a")
+	text, err := collect(svc.ChatCompletionStream(ctx, streamRequest(false)))
+	if err != nil || text != "a " {
+		t.Fatalf("got %q, %v; want the stream cut before the blocked word", text, err)
+	}
+}
+
+func TestGuardrailStreamRewriteOfSentTextBlocks(t *testing.T) {
+	maxLength := guardrails.NewMaxLength(100, guardrails.ActionFlag)
+	remote := &countingGuardrail{}
+	var logs bytes.Buffer
+	svc := newGuardrailService(&fakeLLM{stream: chunkStream("the ", "secret", " is out.")}, &logs, maxLength, remote)
+
+	ctx, _ := requestContext(context.Background(), "key-a")
+	chunkCh, errCh := svc.ChatCompletionStream(ctx, streamRequest(false))
+	var text, reason string
+	for chunk := range chunkCh {
+		for _, choice := range chunk.Choices {
+			text += choice.Delta.Content
+			if choice.FinishReason != nil {
+				reason = *choice.FinishReason
+			}
+		}
+	}
+	if err := <-errCh; err != nil || text != "the secret" || reason != models.FinishReasonContentFilter {
+		t.Fatalf("got %q ending %q, %v", text, reason, err)
+	}
+	if !strings.Contains(logs.String(), "rule=counting action=block") {
+		t.Errorf("forced block not logged under the rewriting rule:\n%s", logs.String())
+	}
+}
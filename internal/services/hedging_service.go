@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// HedgeHeader reports which request of a hedged pair answered, "primary"
+// or "backup".
+const HedgeHeader = "X-Router-Hedge"
+
+// Outcomes reported to the hedging service's onOutcome callback.
+const (
+	HedgeNotNeeded  = "not_needed"
+	HedgePrimaryWon = "primary_won"
+	HedgeBackupWon  = "backup_won"
+	HedgeFailed     = "failed"
+)
+
+// HedgePolicy sends a backup request for requests selected by Matches when
+// the primary has not produced its first token after Delay. BackupModel is
+// the backup's model; empty means the same one.
+type HedgePolicy struct {
+	Matches     func(meta requestmeta.Meta, model string) bool
+	Delay       time.Duration
+	BackupModel string
+}
+
+type hedgingService struct {
+	next      LLMService
+	policies  []HedgePolicy
+	onOutcome func(ctx context.Context, outcome string)
+}
+
+// NewHedgingService wraps next so slow requests are raced against a backup.
+// Whichever produces a first token first is used and the other is
+// cancelled. onOutcome sees how every hedged-eligible request ended. The
+// first matching policy applies.
+func NewHedgingService(next LLMService, policies []HedgePolicy, onOutcome func(ctx context.Context, outcome string)) LLMService {
+	return &hedgingService{
+		next:      next,
+		policies:  policies,
+		onOutcome: onOutcome,
+	}
+}
+
+// hedgeLeg is one of the two racing requests. Each leg gets its own
+// response headers so the two cannot write to the reply at once; the
+// winner's are copied over.
+type hedgeLeg struct {
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	req    *models.ChatCompletionRequest
+	header http.Header
+
+	resp *models.ChatCompletionResponse
+	err  error
+
+	chunkCh  <-chan *models.ChatCompletionChunk
+	errCh    <-chan error
+	pending  []*models.ChatCompletionChunk
+	finished chan struct{}
+}
+
+func (s *hedgingService) newLeg(ctx context.Context, name string, req *models.ChatCompletionRequest) *hedgeLeg {
+	leg := &hedgeLeg{name: name, req: req, header: make(http.Header), finished: make(chan struct{})}
+	meta, _ := requestmeta.Lookup(ctx)
+	meta.ResponseHeader = leg.header
+	leg.ctx, leg.cancel = context.WithCancel(requestmeta.WithMeta(ctx, meta))
+	return leg
+}
+
+func (s *hedgingService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	policy := s.policyFor(ctx, req)
+	if policy == nil {
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	done := make(chan *hedgeLeg, 2)
+	start := func(leg *hedgeLeg) {
+		go func() {
+			leg.resp, leg.err = s.next.ChatCompletion(leg.ctx, leg.req)
+			done <- leg
+		}()
+	}
+
+	legs := []*hedgeLeg{s.newLeg(ctx, "primary", req)}
+	start(legs[0])
+	timer := time.NewTimer(policy.Delay)
+	defer timer.Stop()
+
+	running := 1
+	for {
+		select {
+		case leg := <-done:
+			running--
+			if leg.err == nil {
+				s.finish(ctx, leg, legs)
+				return leg.resp, nil
+			}
+			if running == 0 {
+				s.finish(ctx, leg, legs)
+				return nil, leg.err
+			}
+		case <-timer.C:
+			if running == 1 && len(legs) == 1 {
+				backup := s.newLeg(ctx, "backup", backupRequest(req, policy))
+				legs = append(legs, backup)
+				start(backup)
+				running++
+			}
+		}
+	}
+}
+
+func (s *hedgingService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	policy := s.policyFor(ctx, req)
+	if policy == nil {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	out := make(chan *models.ChatCompletionChunk)
+	outErr := make(chan error, 1)
+
+	go func() {
+		defer close(out)
+		defer close(outErr)
+
+		done := make(chan *hedgeLeg, 2)
+		legs := []*hedgeLeg{s.newLeg(ctx, "primary", req)}
+		s.startStream(legs[0], done)
+		timer := time.NewTimer(policy.Delay)
+		defer timer.Stop()
+
+		running := 1
+		var winner *hedgeLeg
+		for winner == nil {
+			select {
+			case leg := <-done:
+				running--
+				if leg.err == nil {
+					winner = leg
+				} else if running == 0 {
+					s.finish(ctx, leg, legs)
+					outErr <- leg.err
+					return
+				}
+			case <-timer.C:
+				if running == 1 && len(legs) == 1 {
+					backup := s.newLeg(ctx, "backup", backupRequest(req, policy))
+					legs = append(legs, backup)
+					s.startStream(backup, done)
+					running++
+				}
+			case <-ctx.Done():
+				for _, leg := range legs {
+					leg.cancel()
+					go drainLeg(leg)
+				}
+				return
+			}
+		}
+		s.finish(ctx, winner, legs)
+		defer winner.cancel()
+
+		clientGone := false
+		forward := func(chunk *models.ChatCompletionChunk) {
+			if clientGone {
+				return
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				clientGone = true
+			}
+		}
+		for _, chunk := range winner.pending {
+			forward(chunk)
+		}
+		for chunk := range winner.chunkCh {
+			forward(chunk)
+		}
+		select {
+		case err, ok := <-winner.errCh:
+			if ok && err != nil && !clientGone {
+				outErr <- err
+			}
+		default:
+		}
+	}()
+
+	return out, outErr
+}
+
+// startStream opens leg's stream and reports it on done once it produces
+// content, finishes, or fails. Chunks before the first content are held
+// in leg.pending.
+func (s *hedgingService) startStream(leg *hedgeLeg, done chan<- *hedgeLeg) {
+	leg.chunkCh, leg.errCh = s.next.ChatCompletionStream(leg.ctx, leg.req)
+	go func() {
+		defer close(leg.finished)
+		for {
+			select {
+			case chunk, ok := <-leg.chunkCh:
+				if !ok {
+					select {
+					case err, ok := <-leg.errCh:
+						if ok {
+							leg.err = err
+						}
+					default:
+					}
+					done <- leg
+					return
+				}
+				leg.pending = append(leg.pending, chunk)
+				if chunkHasContent(chunk) {
+					done <- leg
+					return
+				}
+			case err, ok := <-leg.errCh:
+				if !ok {
+					leg.errCh = nil
+					continue
+				}
+				if err != nil {
+					leg.err = err
+					done <- leg
+					return
+				}
+			}
+		}
+	}()
+}
+
+// drainLeg consumes the rest of a cancelled leg's stream so its producer
+// never blocks.
+func drainLeg(leg *hedgeLeg) {
+	<-leg.finished
+	if leg.chunkCh == nil {
+		return
+	}
+	for range leg.chunkCh {
+	}
+}
+
+// finish cancels the legs other than winner, copies winner's headers to
+// the reply and reports the outcome.
+func (s *hedgingService) finish(ctx context.Context, winner *hedgeLeg, legs []*hedgeLeg) {
+	for _, leg := range legs {
+		// A winning stream is still being read; everything else is done.
+		if leg == winner && leg.err == nil && leg.chunkCh != nil {
+			continue
+		}
+		leg.cancel()
+		if leg.chunkCh != nil {
+			go drainLeg(leg)
+		}
+	}
+
+	if meta, ok := requestmeta.Lookup(ctx); ok && meta.ResponseHeader != nil {
+		maps.Copy(meta.ResponseHeader, winner.header)
+	}
+
+	outcome := HedgeNotNeeded
+	switch {
+	case len(legs) == 1:
+	case winner.err != nil:
+		outcome = HedgeFailed
+	case winner.name == "primary":
+		outcome = HedgePrimaryWon
+	default:
+		outcome = HedgeBackupWon
+	}
+	if len(legs) > 1 {
+		requestmeta.SetResponseHeader(ctx, HedgeHeader, winner.name)
+	}
+	if s.onOutcome != nil {
+		s.onOutcome(ctx, outcome)
+	}
+}
+
+func (s *hedgingService) policyFor(ctx context.Context, req *models.ChatCompletionRequest) *HedgePolicy {
+	meta := requestmeta.FromContext(ctx)
+	for i := range s.policies {
+		if s.policies[i].Matches(meta, req.Model) {
+			return &s.policies[i]
+		}
+	}
+	return nil
+}
+
+func backupRequest(req *models.ChatCompletionRequest, policy *HedgePolicy) *models.ChatCompletionRequest {
+	if policy.BackupModel == "" {
+		return req
+	}
+	backup := *req
+	backup.Model = policy.BackupModel
+	return &backup
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+const hedgeDelay = 20 * time.Millisecond
+
+// outcomes records the outcomes a hedging service reports.
+type outcomes struct {
+	mu   sync.Mutex
+	seen []string
+}
+
+func (o *outcomes) record(_ context.Context, outcome string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.seen = append(o.seen, outcome)
+}
+
+func (o *outcomes) only(t *testing.T, want string) {
+	t.Helper()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.seen) != 1 || o.seen[0] != want {
+		t.Fatalf("outcomes = %v, want [%s]", o.seen, want)
+	}
+}
+
+// hedgeAll hedges every request after hedgeDelay with the backup model.
+var hedgeAll = []HedgePolicy{{
+	Matches:     func(requestmeta.Meta, string) bool { return true },
+	Delay:       hedgeDelay,
+	BackupModel: "backup",
+}}
+
+// leg describes how the fake upstream answers the primary or backup: after
+// wait, or when release is closed, with text; or err. A leg with neither
+// answers at once.
+type leg struct {
+	wait    time.Duration
+	release chan struct{}
+	text    string
+	err     error
+}
+
+// legs is a fake upstream that answers each model as its leg says and
+// reports when a leg's call has returned.
+type legs struct {
+	byModel map[string]leg
+	mu      sync.Mutex
+	ended   map[string]error
+	endedCh chan string
+}
+
+func newLegs(primary, backup leg) *legs {
+	return &legs{
+		byModel: map[string]leg{"primary": primary, "backup": backup},
+		ended:   make(map[string]error),
+		endedCh: make(chan string, 2),
+	}
+}
+
+func (l *legs) end(model string, err error) {
+	l.mu.Lock()
+	l.ended[model] = err
+	l.mu.Unlock()
+	l.endedCh <- model
+}
+
+// waitEnded waits for model's call to return and reports how it ended.
+func (l *legs) waitEnded(t *testing.T, model string) error {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for {
+		l.mu.Lock()
+		err, ok := l.ended[model]
+		l.mu.Unlock()
+		if ok {
+			return err
+		}
+		select {
+		case <-l.endedCh:
+		case <-deadline:
+			t.Fatalf("%s leg never returned", model)
+		}
+	}
+}
+
+// ready waits until model's leg answers or cancel is closed.
+func (l *legs) ready(cancel <-chan struct{}, model string) error {
+	spec := l.byModel[model]
+	if spec.wait == 0 && spec.release == nil {
+		return spec.err
+	}
+	var timer <-chan time.Time
+	if spec.wait > 0 {
+		timer = time.After(spec.wait)
+	}
+	select {
+	case <-timer:
+	case <-spec.release:
+	case <-cancel:
+		return context.Canceled
+	}
+	return spec.err
+}
+
+func (l *legs) complete(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	requestmeta.SetResponseHeader(ctx, RoutedModelHeader, req.Model)
+	err := l.ready(ctx.Done(), req.Model)
+	l.end(req.Model, err)
+	if err != nil {
+		return nil, err
+	}
+	return textResponse(req.Model, l.byModel[req.Model].text), nil
+}
+
+// stream sends the leg's text one word per chunk. Like a provider that only
+// notices cancellation when its connection drops, it ignores ctx, so a
+// cancelled leg that is not drained never returns.
+func (l *legs) stream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	requestmeta.SetResponseHeader(ctx, RoutedModelHeader, req.Model)
+	chunkCh := make(chan *models.ChatCompletionChunk)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(chunkCh)
+		defer close(errCh)
+		// A role chunk comes first and does not count as the first token.
+		chunkCh <- &models.ChatCompletionChunk{Model: req.Model, Choices: []models.ChatCompletionChunkChoice{{Delta: models.ChatMessage{Role: "assistant"}}}}
+		if err := l.ready(nil, req.Model); err != nil {
+			errCh <- err
+			l.end(req.Model, err)
+			return
+		}
+		for _, w := range []string{l.byModel[req.Model].text, " and more"} {
+			chunkCh <- textChunk(req.Model, w)
+		}
+		l.end(req.Model, ctx.Err())
+	}()
+	return chunkCh, errCh
+}
+
+func TestHedgingNotNeeded(t *testing.T) {
+	up := newLegs(leg{text: "primary"}, leg{text: "backup"})
+	next := &fakeLLM{complete: up.complete}
+	seen := &outcomes{}
+	svc := NewHedgingService(next, hedgeAll, seen.record)
+
+	ctx, header := requestContext(context.Background(), "key-a")
+	resp, err := svc.ChatCompletion(ctx, &models.ChatCompletionRequest{Model: "primary"})
+	if err != nil || resp.Choices[0].Message.Content != "primary" {
+		t.Fatalf("got %v, %v", resp, err)
+	}
+	if n := next.calls.Load(); n != 1 {
+		t.Fatalf("upstream calls = %d, want 1", n)
+	}
+	if header.Get(HedgeHeader) != "" || header.Get(RoutedModelHeader) != "primary" {
+		t.Errorf("headers = %v", header)
+	}
+	seen.only(t, HedgeNotNeeded)
+}
+
+func TestHedgingCompletion(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary leg
+		backup  leg
+		winner  string
+		outcome string
+	}{
+		{
+			name:    "backup wins",
+			primary: leg{release: make(chan struct{}), text: "primary"},
+			backup:  leg{text: "backup"},
+			winner:  "backup",
+			outcome: HedgeBackupWon,
+		},
+		{
+			name:    "primary wins after backup starts",
+			primary: leg{wait: 2 * hedgeDelay, text: "primary"},
+			backup:  leg{release: make(chan struct{}), text: "backup"},
+			winner:  "primary",
+			outcome: HedgePrimaryWon,
+		},
+		{
+			name:    "failed primary leaves it to the backup",
+			primary: leg{wait: 2 * hedgeDelay, err: errors.New("upstream failed")},
+			backup:  leg{wait: 4 * hedgeDelay, text: "backup"},
+			winner:  "backup",
+			outcome: HedgeBackupWon,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newLegs(tt.primary, tt.backup)
+			seen := &outcomes{}
+			svc := NewHedgingService(&fakeLLM{complete: up.complete}, hedgeAll, seen.record)
+
+			ctx, header := requestContext(context.Background(), "key-a")
+			resp, err := svc.ChatCompletion(ctx, &models.ChatCompletionRequest{Model: "primary"})
+			if err != nil || resp.Choices[0].Message.Content != tt.winner {
+				t.Fatalf("got %v, %v; want %s's answer", resp, err, tt.winner)
+			}
+			if header.Get(HedgeHeader) != tt.winner || header.Get(RoutedModelHeader) != tt.winner {
+				t.Errorf("headers = %v, want %s's", header, tt.winner)
+			}
+			seen.only(t, tt.outcome)
+
+			loser := "primary"
+			if tt.winner == "primary" {
+				loser = "backup"
+			}
+			if up.byModel[loser].err == nil {
+				if err := up.waitEnded(t, loser); !errors.Is(err, context.Canceled) {
+					t.Errorf("losing %s leg ended with %v, want it cancelled", loser, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHedgingCompletionBothFail(t *testing.T) {
+	primaryErr := errors.New("primary failed")
+	up := newLegs(leg{wait: 2 * hedgeDelay, err: primaryErr}, leg{wait: 4 * hedgeDelay, err: errors.New("backup failed")})
+	seen := &outcomes{}
+	svc := NewHedgingService(&fakeLLM{complete: up.complete}, hedgeAll, seen.record)
+
+	ctx, _ := requestContext(context.Background(), "key-a")
+	if _, err := svc.ChatCompletion(ctx, &models.ChatCompletionRequest{Model: "primary"}); err == nil || errors.Is(err, primaryErr) {
+		t.Fatalf("err = %v, want the last leg's error", err)
+	}
+	seen.only(t, HedgeFailed)
+}
+
+func TestHedgingStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary leg
+		backup  leg
+		winner  string
+		outcome string
+	}{
+		{
+			name:    "primary wins",
+			primary: leg{text: "primary"},
+			winner:  "primary",
+			outcome: HedgeNotNeeded,
+		},
+		{
+			name:    "backup wins",
+			primary: leg{release: make(chan struct{}), text: "primary"},
+			backup:  leg{text: "backup"},
+			winner:  "backup",
+			outcome: HedgeBackupWon,
+		},
+		{
+			name:    "primary wins after backup starts",
+			primary: leg{wait: 2 * hedgeDelay, text: "primary"},
+			backup:  leg{release: make(chan struct{}), text: "backup"},
+			winner:  "primary",
+			outcome: HedgePrimaryWon,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			up := newLegs(tt.primary, tt.backup)
+			next := &fakeLLM{stream: up.stream}
+			seen := &outcomes{}
+			svc := NewHedgingService(next, hedgeAll, seen.record)
+
+			ctx, header := requestContext(context.Background(), "key-a")
+			text, err := collect(svc.ChatCompletionStream(ctx, &models.ChatCompletionRequest{Model: "primary", Stream: true}))
+			if err != nil || text != tt.winner+" and more" {
+				t.Fatalf("got %q, %v; want %s's stream", text, err, tt.winner)
+			}
+			if header.Get(RoutedModelHeader) != tt.winner {
+				t.Errorf("headers = %v, want %s's", header, tt.winner)
+			}
+			seen.only(t, tt.outcome)
+
+			if next.calls.Load() == 1 {
+				return
+			}
+			loser := "primary"
+			if tt.winner == "primary" {
+				loser = "backup"
+			}
+			// Had the loser's stream not been drained, its producer would
+			// block on its first word forever.
+			close(up.byModel[loser].release)
+			if err := up.waitEnded(t, loser); !errors.Is(err, context.Canceled) {
+				t.Errorf("losing %s leg ended with %v, want it cancelled", loser, err)
+			}
+		})
+	}
+}
+
+func TestHedgingStreamClientLeaves(t *testing.T) {
+	up := newLegs(leg{release: make(chan struct{}), text: "primary"}, leg{release: make(chan struct{}), text: "backup"})
+	next := &fakeLLM{stream: up.stream}
+	svc := NewHedgingService(next, hedgeAll, new(outcomes).record)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, _ = requestContext(ctx, "key-a")
+	chunkCh, errCh := svc.ChatCompletionStream(ctx, &models.ChatCompletionRequest{Model: "primary", Stream: true})
+	waitFor(t, "the backup", func() bool { return next.calls.Load() == 2 })
+	cancel()
+	collect(chunkCh, errCh)
+	close(up.byModel["primary"].release)
+	close(up.byModel["backup"].release)
+
+	for _, model := range []string{"primary", "backup"} {
+		if err := up.waitEnded(t, model); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s leg ended with %v, want it cancelled", model, err)
+		}
+	}
+}
@@ -3,9 +3,14 @@ package services
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
+	"github.com/llm-router/internal/accounting"
 	"github.com/llm-router/internal/models"
 	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/llm-router/internal/tracing"
 )
 
 type LLMService interface {
@@ -15,36 +20,75 @@ type LLMService interface {
 
 type LLMServiceImpl struct {
 	providerFactory *providers.ProviderFactory
+	recorder        accounting.Recorder
+	logger          *slog.Logger
 }
 
-func NewLLMService(providerFactory *providers.ProviderFactory) LLMService {
-	return &LLMServiceImpl{providerFactory: providerFactory}
+func NewLLMService(providerFactory *providers.ProviderFactory, recorder accounting.Recorder, logger *slog.Logger) LLMService {
+	return &LLMServiceImpl{
+		providerFactory: providerFactory,
+		recorder:        recorder,
+		logger:          logger,
+	}
 }
 
 func (s *LLMServiceImpl) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "LLMService.ChatCompletion")
+	defer span.End()
+
 	provider, err := s.providerFactory.GetProvider(req.Model)
 	if err != nil {
-		return nil, fmt.Errorf("provider not found for model %s: %w", req.Model, err)
+		err = fmt.Errorf("provider not found for model %s: %w", req.Model, err)
+		tracing.RecordError(span, err)
+		return nil, err
 	}
 
+	start := time.Now()
 	response, err := provider.ChatCompletion(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("LLM service error: %w", err)
+		s.logger.WarnContext(ctx, "chat completion failed",
+			slog.String("provider", provider.Name()),
+			slog.String("model", req.Model),
+			slog.String("error_class", providers.ErrorClass(err)),
+			slog.Any("error", err),
+		)
+		err = fmt.Errorf("LLM service error: %w", err)
+		tracing.RecordError(span, err)
+		return nil, err
 	}
+
+	s.recorder.Record(ctx, accounting.Record{
+		RequestID: requestmeta.FromContext(ctx).RequestID,
+		Provider:  provider.Name(),
+		Model:     response.Model,
+		Usage:     response.Usage,
+		Cost:      accounting.Cost(response.Model, response.Usage),
+		Request:   req,
+		Response:  response,
+		Latency:   time.Since(start),
+		Time:      time.Now(),
+	})
 	return response, nil
 }
 
 func (s *LLMServiceImpl) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
 	errCh := make(chan error, 1)
 
+	// The span is ended by meterStream once the stream has finished.
+	ctx, span := tracing.Tracer().Start(ctx, "LLMService.ChatCompletionStream")
+
 	provider, err := s.providerFactory.GetProvider(req.Model)
 	if err != nil {
-		errCh <- fmt.Errorf("provider not found for model %s: %w", req.Model, err)
+		err = fmt.Errorf("provider not found for model %s: %w", req.Model, err)
+		tracing.RecordError(span, err)
+		span.End()
+		errCh <- err
 		close(errCh)
 		return nil, errCh
 	}
 
+	start := time.Now()
 	chunkCh, providerErrCh := provider.ChatCompletionStream(ctx, req)
 
-	return chunkCh, providerErrCh
+	return s.meterStream(ctx, provider, req, start, chunkCh, providerErrCh)
 }
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/pii"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// PIIPolicy redacts the entities found by Detector from requests selected by
+// Matches.
+type PIIPolicy struct {
+	Matches  func(meta requestmeta.Meta, model string) bool
+	Detector *pii.Detector
+}
+
+type piiService struct {
+	next     LLMService
+	policies []PIIPolicy
+	logger   *slog.Logger
+}
+
+// NewPIIService wraps next so that PII in request messages is replaced with
+// placeholders before it leaves the router, and the placeholders are
+// restored in the model's output. The first matching policy applies.
+func NewPIIService(next LLMService, policies []PIIPolicy, logger *slog.Logger) LLMService {
+	return &piiService{
+		next:     next,
+		policies: policies,
+		logger:   logger,
+	}
+}
+
+func (s *piiService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	redactor, redacted := s.redact(ctx, req)
+	if redactor == nil {
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	resp, err := s.next.ChatCompletion(ctx, redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	resp = copyResponse(resp)
+	for i := range resp.Choices {
+		resp.Choices[i].Message.Content = redactor.Restore(resp.Choices[i].Message.Content)
+	}
+	return resp, nil
+}
+
+func (s *piiService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	redactor, redacted := s.redact(ctx, req)
+	if redactor == nil {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, redacted)
+
+	restorers := make(map[int]*pii.StreamRestorer)
+	var last models.ChatCompletionChunk
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			last = *chunk
+			c := *chunk
+			c.Choices = append([]models.ChatCompletionChunkChoice(nil), chunk.Choices...)
+			for i := range c.Choices {
+				choice := &c.Choices[i]
+				restorer, ok := restorers[choice.Index]
+				if !ok {
+					restorer = redactor.NewStreamRestorer()
+					restorers[choice.Index] = restorer
+				}
+				choice.Delta.Content = restorer.Write(choice.Delta.Content)
+				if choice.FinishReason != nil {
+					choice.Delta.Content += restorer.Flush()
+				}
+			}
+			return &c
+		},
+		// A stream that ends without a finish reason, or fails, still
+		// gets whatever text was held back.
+		OnEnd: func() []*models.ChatCompletionChunk {
+			var rest []models.ChatCompletionChunkChoice
+			for index, restorer := range restorers {
+				if text := restorer.Flush(); text != "" {
+					rest = append(rest, models.ChatCompletionChunkChoice{
+						Index: index,
+						Delta: models.ChatMessage{Content: text},
+					})
+				}
+			}
+			if len(rest) == 0 {
+				return nil
+			}
+			slices.SortFunc(rest, func(a, b models.ChatCompletionChunkChoice) int { return a.Index - b.Index })
+			return []*models.ChatCompletionChunk{{
+				ID:      last.ID,
+				Object:  last.Object,
+				Created: last.Created,
+				Model:   last.Model,
+				Choices: rest,
+			}}
+		},
+	})
+}
+
+// redact returns a copy of req with PII replaced, and the redactor holding
+// the originals. Both are nil when no policy matches or nothing was found.
+func (s *piiService) redact(ctx context.Context, req *models.ChatCompletionRequest) (*pii.Redactor, *models.ChatCompletionRequest) {
+	meta := requestmeta.FromContext(ctx)
+	for _, policy := range s.policies {
+		if !policy.Matches(meta, req.Model) {
+			continue
+		}
+
+		redactor := policy.Detector.NewRedactor()
+		redacted := *req
+		redacted.Messages = append(redacted.Messages[:0:0], req.Messages...)
+		for i := range redacted.Messages {
+			redacted.Messages[i].Content = redactor.Redact(redacted.Messages[i].Content)
+		}
+		if !redactor.Redacted() {
+			return nil, nil
+		}
+
+		s.logger.DebugContext(ctx, "redacted PII from request", slog.String("model", req.Model))
+		return redactor, &redacted
+	}
+	return nil, nil
+}
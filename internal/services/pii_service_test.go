@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/pii"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+func newPIIService(t *testing.T, next LLMService) LLMService {
+	t.Helper()
+	detector, err := pii.NewDetector([]string{pii.Email}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewPIIService(next, []PIIPolicy{{
+		Matches:  func(requestmeta.Meta, string) bool { return true },
+		Detector: detector,
+	}}, discardLogger())
+}
+
+func TestPIICompletion(t *testing.T) {
+	var sent string
+	upstream := &fakeLLM{complete: func(_ context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+		sent = req.Messages[0].Content
+		return textResponse(req.Model, "Sent to [EMAIL_1]."), nil
+	}}
+	svc := newPIIService(t, upstream)
+
+	ctx, _ := requestContext(context.Background(), "key-a")
+	resp, err := svc.ChatCompletion(ctx, newRequest("gpt-4o", "write to ada@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != "write to [EMAIL_1]" {
+		t.Errorf("upstream saw %q", sent)
+	}
+	if got := resp.Choices[0].Message.Content; got != "Sent to ada@example.com." {
+		t.Errorf("reply = %q", got)
+	}
+}
+
+func TestPIIStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+		words  []string
+		want   string
+	}{
+		{"placeholder split over chunks", "stop", []string{"to [EMA", "IL_", "1] now"}, "to ada@example.com now"},
+		{"placeholder completed by the last chunk", "stop", []string{"to [EMAIL_", "1]"}, "to ada@example.com"},
+		{"held text flushed when the stream ends early", "", []string{"to [EMAIL_", "1"}, "to [EMAIL_1"},
+		{"held text flushed with the finish reason", "length", []string{"to [EM"}, "to [EM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newPIIService(t, &fakeLLM{stream: wordStream(tt.reason, tt.words...)})
+
+			req := streamRequest(false)
+			req.Messages[0].Content = "mail ada@example.com"
+			ctx, _ := requestContext(context.Background(), "key-a")
+			text, err := collect(svc.ChatCompletionStream(ctx, req))
+			if err != nil || text != tt.want {
+				t.Errorf("got %q, %v; want %q", text, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPIIStreamFailureFlushesHeldText(t *testing.T) {
+	upstreamErr := errors.New("upstream failed")
+	upstream := &fakeLLM{stream: func(_ context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+		chunkCh := make(chan *models.ChatCompletionChunk, 1)
+		errCh := make(chan error, 1)
+		chunkCh <- textChunk(req.Model, "to [EMAIL_")
+		close(chunkCh)
+		errCh <- upstreamErr
+		close(errCh)
+		return chunkCh, errCh
+	}}
+	svc := newPIIService(t, upstream)
+
+	req := streamRequest(false)
+	req.Messages[0].Content = "mail ada@example.com"
+	ctx, _ := requestContext(context.Background(), "key-a")
+	text, err := collect(svc.ChatCompletionStream(ctx, req))
+	if !errors.Is(err, upstreamErr) || text != "to [EMAIL_" {
+		t.Errorf("got %q, %v; want the held text and the upstream error", text, err)
+	}
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/llm-router/internal/routing"
+)
+
+// RoutedModelHeader names the model a routed alias was sent to.
+const RoutedModelHeader = "X-Router-Model"
+
+type routingService struct {
+	next   LLMService
+	router *routing.Router
+	logger *slog.Logger
+}
+
+// NewRoutingService wraps next so requests for a routed alias go to the
+// target the router picks, and feeds each outcome back to the router.
+func NewRoutingService(next LLMService, router *routing.Router, logger *slog.Logger) LLMService {
+	return &routingService{
+		next:   next,
+		router: router,
+		logger: logger,
+	}
+}
+
+func (s *routingService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	routed, ok := s.route(ctx, req, false)
+	if !ok {
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := s.next.ChatCompletion(ctx, routed)
+	s.observe(ctx, routed.Model, false, time.Since(start), err)
+	return resp, err
+}
+
+func (s *routingService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	routed, ok := s.route(ctx, req, true)
+	if !ok {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	start := time.Now()
+	var ttft time.Duration
+	var streamErr error
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, routed)
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			if ttft == 0 && chunkHasContent(chunk) {
+				ttft = time.Since(start)
+			}
+			return chunk
+		},
+		OnError: func(err error) {
+			streamErr = err
+		},
+		OnDone: func() {
+			if ttft == 0 {
+				ttft = time.Since(start)
+			}
+			s.observe(ctx, routed.Model, true, ttft, streamErr)
+		},
+	})
+}
+
+func (s *routingService) route(ctx context.Context, req *models.ChatCompletionRequest, stream bool) (*models.ChatCompletionRequest, bool) {
+	target, ok := s.router.Pick(req.Model, stream)
+	if !ok {
+		return nil, false
+	}
+	s.logger.DebugContext(ctx, "routed alias", slog.String("alias", req.Model), slog.String("model", target))
+	requestmeta.SetResponseHeader(ctx, RoutedModelHeader, target)
+
+	routed := *req
+	routed.Model = target
+	return &routed, true
+}
+
+// observe records an outcome unless it says nothing about the target, as
+// with invalid requests and callers that went away.
+func (s *routingService) observe(ctx context.Context, target string, stream bool, latency time.Duration, err error) {
+	var reqErr *RequestError
+	if ctx.Err() != nil || errors.As(err, &reqErr) || providers.ErrorClass(err) == "invalid_request" {
+		return
+	}
+	s.router.Observe(target, stream, latency, err != nil)
+}
+
+func chunkHasContent(chunk *models.ChatCompletionChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Content != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/llm-router/internal/cache"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+const (
+	// SemanticCacheHeader is set to HIT or MISS on replies that went through
+	// the semantic cache; SemanticScoreHeader carries the similarity of a hit.
+	SemanticCacheHeader = "X-Router-Semantic-Cache"
+	SemanticScoreHeader = "X-Router-Semantic-Score"
+)
+
+type SemanticCacheOptions struct {
+	TTL       time.Duration
+	Mode      string
+	Threshold float32
+}
+
+type semanticCachingService struct {
+	next     LLMService
+	embedder providers.Embedder
+	index    *cache.VectorIndex
+	opts     SemanticCacheOptions
+	logger   *slog.Logger
+}
+
+// NewSemanticCachingService wraps next with a cache that matches on the
+// meaning of the final user message rather than its exact text. Matches are
+// only considered within the same client key, model, sampling parameters
+// and earlier messages.
+func NewSemanticCachingService(next LLMService, embedder providers.Embedder, index *cache.VectorIndex, opts SemanticCacheOptions, logger *slog.Logger) LLMService {
+	return &semanticCachingService{
+		next:     next,
+		embedder: embedder,
+		index:    index,
+		opts:     opts,
+		logger:   logger,
+	}
+}
+
+func (s *semanticCachingService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	scope, vector, ok := s.lookupVector(ctx, req)
+	if !ok {
+		return s.next.ChatCompletion(ctx, req)
+	}
+	if cached, score, hit := s.index.Search(scope, vector, s.opts.Threshold); hit {
+		setSemanticHit(ctx, score)
+		return copyResponse(cached), nil
+	}
+	requestmeta.SetResponseHeader(ctx, SemanticCacheHeader, "MISS")
+
+	resp, err := s.next.ChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if complete(resp) {
+		s.index.Add(scope, vector, copyResponse(resp), s.opts.TTL)
+	}
+	return resp, nil
+}
+
+func (s *semanticCachingService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	scope, vector, ok := s.lookupVector(ctx, req)
+	if !ok {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+	if cached, score, hit := s.index.Search(scope, vector, s.opts.Threshold); hit {
+		setSemanticHit(ctx, score)
+		return replayStream(ctx, cached, req.IncludeUsage())
+	}
+	requestmeta.SetResponseHeader(ctx, SemanticCacheHeader, "MISS")
+
+	upstreamReq := *req
+	upstreamReq.StreamOptions = &models.StreamOptions{IncludeUsage: true}
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, &upstreamReq)
+
+	acc := models.NewChunkAccumulator()
+	failed := false
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			acc.Add(chunk)
+			return usageForClient(chunk, req)
+		},
+		OnError: func(err error) {
+			failed = true
+		},
+		OnDone: func() {
+			resp := acc.Response()
+			if !failed && complete(resp) {
+				s.index.Add(scope, vector, resp, s.opts.TTL)
+			}
+		},
+	})
+}
+
+// lookupVector embeds the final user message. The scope it returns hashes
+// everything else about the request, so only the final message is matched
+// by meaning. It reports false when the request should bypass the cache or
+// the embedding could not be computed.
+func (s *semanticCachingService) lookupVector(ctx context.Context, req *models.ChatCompletionRequest) (string, []float32, bool) {
+	if !cacheAllowed(ctx, req, s.opts.Mode) {
+		return "", nil, false
+	}
+
+	last := lastUserMessage(req)
+	if last < 0 || req.Messages[last].Content == "" {
+		return "", nil, false
+	}
+
+	vector, err := s.embedder.Embed(ctx, req.Messages[last].Content)
+	if err != nil {
+		s.logger.WarnContext(ctx, "semantic cache lookup skipped",
+			slog.String("model", req.Model),
+			slog.Any("error", err),
+		)
+		return "", nil, false
+	}
+
+	rest := *req
+	rest.Messages = append(req.Messages[:last:last], req.Messages[last+1:]...)
+	scope := cache.Key(requestmeta.FromContext(ctx).Key, &rest)
+	return scope, vector, true
+}
+
+func setSemanticHit(ctx context.Context, score float32) {
+	requestmeta.SetResponseHeader(ctx, SemanticCacheHeader, "HIT")
+	requestmeta.SetResponseHeader(ctx, SemanticScoreHeader, strconv.FormatFloat(float64(score), 'f', 4, 32))
+}
+
+// lastUserMessage returns the index of the final user message, or -1.
+func lastUserMessage(req *models.ChatCompletionRequest) int {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role == "user" {
+			return i
+		}
+	}
+	return -1
+}
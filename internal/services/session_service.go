@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/llm-router/internal/sessions"
+)
+
+type sessionService struct {
+	next   LLMService
+	store  sessions.Store
+	logger *slog.Logger
+}
+
+// NewSessionService wraps next so a request naming a session is sent with
+// the session's history in front of its messages, after any messages its
+// template rendered. Once the completion finishes, the client's messages
+// and the reply are added to the session; template output is not, since
+// the template renders it again on the next turn. Completions stopped by a
+// content filter are not recorded.
+func NewSessionService(next LLMService, store sessions.Store, logger *slog.Logger) LLMService {
+	return &sessionService{
+		next:   next,
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (s *sessionService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	if req.SessionID == "" {
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	withHistory, err := s.load(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.next.ChatCompletion(ctx, withHistory)
+	if err != nil {
+		return nil, err
+	}
+	s.save(ctx, req, resp)
+	return resp, nil
+}
+
+func (s *sessionService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	if req.SessionID == "" {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	withHistory, err := s.load(ctx, req)
+	if err != nil {
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(errCh)
+		return nil, errCh
+	}
+
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, withHistory)
+
+	acc := models.NewChunkAccumulator()
+	failed := false
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			acc.Add(chunk)
+			return chunk
+		},
+		OnError: func(err error) {
+			failed = true
+		},
+		OnDone: func() {
+			if resp := acc.Response(); !failed && finished(resp) {
+				s.save(context.WithoutCancel(ctx), req, resp)
+			}
+		},
+	})
+}
+
+func (s *sessionService) load(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionRequest, error) {
+	session, err := s.store.Get(ctx, requestmeta.FromContext(ctx).Key, req.SessionID)
+	if errors.Is(err, sessions.ErrNotFound) {
+		return nil, &RequestError{Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := req.RenderedMessages()
+	withHistory := *req
+	withHistory.Messages = make([]models.Message, 0, len(session.Messages)+len(req.Messages))
+	withHistory.Messages = append(withHistory.Messages, req.Messages[:rendered]...)
+	withHistory.Messages = append(withHistory.Messages, session.Messages...)
+	withHistory.Messages = append(withHistory.Messages, req.Messages[rendered:]...)
+	return &withHistory, nil
+}
+
+func (s *sessionService) save(ctx context.Context, req *models.ChatCompletionRequest, resp *models.ChatCompletionResponse) {
+	if len(resp.Choices) == 0 || resp.Choices[0].FinishReason == models.FinishReasonContentFilter {
+		return
+	}
+
+	reply := resp.Choices[0].Message
+	if reply.Role == "" {
+		reply.Role = "assistant"
+	}
+	sent := req.Messages[req.RenderedMessages():]
+	messages := append(sent[:len(sent):len(sent)], models.Message{
+		Role:    reply.Role,
+		Content: reply.Content,
+	})
+	if err := s.store.Append(ctx, requestmeta.FromContext(ctx).Key, req.SessionID, messages...); err != nil {
+		s.logger.WarnContext(ctx, "failed to save session turn",
+			slog.String("session_id", req.SessionID),
+			slog.Any("error", err),
+		)
+	}
+}
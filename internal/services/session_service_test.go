@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/prompts"
+	"github.com/llm-router/internal/sessions"
+)
+
+func turns(messages []models.Message) []string {
+	var out []string
+	for _, m := range messages {
+		out = append(out, m.Role+":"+m.Content)
+	}
+	return out
+}
+
+func TestSessionKeepsTemplateOutOfHistory(t *testing.T) {
+	templates, err := prompts.NewStore([]prompts.Template{{
+		ID:       "support",
+		Version:  1,
+		Messages: []models.Message{{Role: "system", Content: "You help with {{.Vars.product}}."}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := sessions.NewMemoryStore()
+	now := time.Now()
+	if err := store.Create(context.Background(), &sessions.Session{ID: "sess-1", Key: "key-a", CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []*models.ChatCompletionRequest
+	upstream := &fakeLLM{complete: func(_ context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+		sent = append(sent, req)
+		return textResponse(req.Model, "reply "+req.Messages[len(req.Messages)-1].Content), nil
+	}}
+	logger := discardLogger()
+	svc := NewTemplateService(NewSessionService(upstream, store, logger), templates, logger)
+
+	for _, text := range []string{"one", "two"} {
+		ctx, _ := requestContext(context.Background(), "key-a")
+		req := newRequest("gpt-4o", text)
+		req.SessionID = "sess-1"
+		req.Template = &models.TemplateRef{ID: "support", Variables: map[string]string{"product": "routers"}}
+		if _, err := svc.ChatCompletion(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"system:You help with routers.", "user:one", "assistant:reply one", "user:two"}
+	if got := turns(sent[1].Messages); !reflect.DeepEqual(got, want) {
+		t.Errorf("second turn sent %v, want %v", got, want)
+	}
+	s, err := store.Get(context.Background(), "key-a", "sess-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"user:one", "assistant:reply one", "user:two", "assistant:reply two"}
+	if got := turns(s.Messages); !reflect.DeepEqual(got, want) {
+		t.Errorf("stored history %v, want %v", got, want)
+	}
+}
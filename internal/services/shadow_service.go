@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/llm-router/internal/accounting"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/llm-router/internal/shadow"
+	"github.com/llm-router/internal/tokenizer"
+)
+
+// ShadowPolicy mirrors SampleRate of the requests selected by Matches to
+// Model.
+type ShadowPolicy struct {
+	Matches    func(meta requestmeta.Meta, model string) bool
+	Model      string
+	SampleRate float64
+}
+
+type shadowService struct {
+	next     LLMService
+	policies []ShadowPolicy
+	mirror   *shadow.Mirror
+}
+
+// NewShadowService wraps next so sampled requests are also sent to a shadow
+// model once the client's answer is complete. The client only ever sees
+// the production answer. The first matching policy applies.
+func NewShadowService(next LLMService, policies []ShadowPolicy, mirror *shadow.Mirror) LLMService {
+	return &shadowService{
+		next:     next,
+		policies: policies,
+		mirror:   mirror,
+	}
+}
+
+func (s *shadowService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	policy := s.sample(ctx, req)
+	if policy == nil {
+		return s.next.ChatCompletion(ctx, req)
+	}
+
+	start := time.Now()
+	resp, err := s.next.ChatCompletion(ctx, req)
+	c := newComparison(ctx, req, policy, time.Since(start))
+	if err != nil {
+		c.Primary.Error = err.Error()
+	} else {
+		c.Primary.SetResponse(resp)
+	}
+	s.submit(ctx, req, c)
+	return resp, err
+}
+
+func (s *shadowService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	policy := s.sample(ctx, req)
+	if policy == nil {
+		return s.next.ChatCompletionStream(ctx, req)
+	}
+
+	start := time.Now()
+	acc := models.NewChunkAccumulator()
+	var streamErr error
+	chunkCh, errCh := s.next.ChatCompletionStream(ctx, req)
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			acc.Add(chunk)
+			return chunk
+		},
+		OnError: func(err error) {
+			streamErr = err
+		},
+		OnDone: func() {
+			c := newComparison(ctx, req, policy, time.Since(start))
+			if streamErr != nil {
+				c.Primary.Error = streamErr.Error()
+			}
+			resp := acc.Response()
+			if resp.Model != "" {
+				c.Primary.Model = resp.Model
+			}
+			c.Primary.Output = acc.Content()
+			if usage := acc.Usage(); usage != nil {
+				c.Primary.Usage = *usage
+			} else {
+				tok := tokenizer.ForModel(req.Model)
+				c.Primary.Usage.PromptTokens = tok.CountMessages(req.Messages)
+				c.Primary.Usage.CompletionTokens = tok.Count(c.Primary.Output)
+				c.Primary.Usage.TotalTokens = c.Primary.Usage.PromptTokens + c.Primary.Usage.CompletionTokens
+			}
+			c.Primary.Cost = accounting.Cost(c.Primary.Model, c.Primary.Usage)
+			s.submit(ctx, req, c)
+		},
+	})
+}
+
+// submit mirrors the request unless the client went away first, in which
+// case there is no complete production answer to compare with.
+func (s *shadowService) submit(ctx context.Context, req *models.ChatCompletionRequest, c *shadow.Comparison) {
+	if ctx.Err() != nil {
+		return
+	}
+	s.mirror.Submit(ctx, req, c)
+}
+
+func (s *shadowService) sample(ctx context.Context, req *models.ChatCompletionRequest) *ShadowPolicy {
+	meta := requestmeta.FromContext(ctx)
+	for i := range s.policies {
+		if s.policies[i].Matches(meta, req.Model) {
+			if rand.Float64() < s.policies[i].SampleRate {
+				return &s.policies[i]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+func newComparison(ctx context.Context, req *models.ChatCompletionRequest, policy *ShadowPolicy, latency time.Duration) *shadow.Comparison {
+	meta := requestmeta.FromContext(ctx)
+	return &shadow.Comparison{
+		Time:      time.Now(),
+		RequestID: meta.RequestID,
+		Key:       meta.Key,
+		Route:     meta.Route,
+		Request:   req,
+		Primary:   shadow.Result{Model: req.Model, Latency: latency},
+		Shadow:    shadow.Result{Model: policy.Model},
+	}
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/llm-router/internal/accounting"
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"github.com/llm-router/internal/requestmeta"
+	"github.com/llm-router/internal/tokenizer"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// meterStream sits between a provider stream and the client. It reassembles
+// the stream for accounting and only forwards the final usage chunk when the
+// client asked for stream_options.include_usage. The relay keeps draining
+// the provider after the client has gone away so the call is still recorded.
+func (s *LLMServiceImpl) meterStream(
+	ctx context.Context,
+	provider providers.Provider,
+	req *models.ChatCompletionRequest,
+	start time.Time,
+	chunkCh <-chan *models.ChatCompletionChunk,
+	errCh <-chan error,
+) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	acc := models.NewChunkAccumulator()
+	received := false
+
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			received = true
+			acc.Add(chunk)
+			return usageForClient(chunk, req)
+		},
+		OnError: func(err error) {
+			s.logger.WarnContext(ctx, "chat completion stream failed",
+				slog.String("provider", provider.Name()),
+				slog.String("model", req.Model),
+				slog.String("error_class", providers.ErrorClass(err)),
+				slog.Any("error", err),
+			)
+		},
+		OnDone: func() {
+			defer trace.SpanFromContext(ctx).End()
+
+			// Nothing came back, so the upstream call failed before billing.
+			if !received {
+				return
+			}
+			s.recordStream(ctx, provider, req, start, acc)
+		},
+	})
+}
+
+// usageForClient strips usage from a chunk unless the client opted in. A
+// usage-only chunk is dropped entirely.
+func usageForClient(chunk *models.ChatCompletionChunk, req *models.ChatCompletionRequest) *models.ChatCompletionChunk {
+	if chunk.Usage == nil || req.IncludeUsage() {
+		return chunk
+	}
+	if len(chunk.Choices) == 0 {
+		return nil
+	}
+	stripped := *chunk
+	stripped.Usage = nil
+	return &stripped
+}
+
+func (s *LLMServiceImpl) recordStream(
+	ctx context.Context,
+	provider providers.Provider,
+	req *models.ChatCompletionRequest,
+	start time.Time,
+	acc *models.ChunkAccumulator,
+) {
+	response := acc.Response()
+	if response.Model == "" {
+		response.Model = req.Model
+	}
+
+	rec := accounting.Record{
+		RequestID: requestmeta.FromContext(ctx).RequestID,
+		Provider:  provider.Name(),
+		Model:     response.Model,
+		Stream:    true,
+		Request:   req,
+		Response:  response,
+		Latency:   time.Since(start),
+		Time:      time.Now(),
+	}
+
+	if usage := acc.Usage(); usage != nil {
+		rec.Usage = *usage
+	} else {
+		tok := tokenizer.ForModel(req.Model)
+		rec.Usage.PromptTokens = tok.CountMessages(req.Messages)
+		rec.Usage.CompletionTokens = tok.Count(acc.Content())
+		rec.Usage.TotalTokens = rec.Usage.PromptTokens + rec.Usage.CompletionTokens
+		rec.Estimated = true
+		response.Usage = rec.Usage
+	}
+	rec.Cost = accounting.Cost(rec.Model, rec.Usage)
+
+	s.recorder.Record(context.WithoutCancel(ctx), rec)
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"text/template"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/prompts"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// TemplateHeader names the template and version a request was built from,
+// e.g. "support-agent@3".
+const TemplateHeader = "X-Router-Template"
+
+// System prompt modes.
+const (
+	// SystemPromptPrepend puts the system prompt before the client's
+	// messages, including any system messages the client sent.
+	SystemPromptPrepend = "prepend"
+	// SystemPromptEnforce replaces the client's system messages.
+	SystemPromptEnforce = "enforce"
+)
+
+// SystemPromptPolicy adds Content, rendered as a template, to requests
+// selected by Matches.
+type SystemPromptPolicy struct {
+	Matches func(meta requestmeta.Meta, model string) bool
+	Mode    string
+	Content *template.Template
+}
+
+type templateService struct {
+	next   LLMService
+	store  *prompts.Store
+	logger *slog.Logger
+}
+
+// NewTemplateService wraps next so requests can name a stored prompt
+// template instead of sending the whole prompt. It sits outside the session
+// layer, which keeps the rendered messages out of the stored history, so a
+// template is rendered afresh on every turn that names it.
+func NewTemplateService(next LLMService, store *prompts.Store, logger *slog.Logger) LLMService {
+	return &templateService{
+		next:   next,
+		store:  store,
+		logger: logger,
+	}
+}
+
+func (s *templateService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	rendered, err := s.render(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.ChatCompletion(ctx, rendered)
+}
+
+func (s *templateService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	rendered, err := s.render(ctx, req)
+	if err != nil {
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(errCh)
+		return nil, errCh
+	}
+	return s.next.ChatCompletionStream(ctx, rendered)
+}
+
+// render returns a copy of req with the template expanded in front of its
+// messages. The template reference in the copy records the version that
+// was used and how many messages it rendered.
+func (s *templateService) render(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionRequest, error) {
+	if req.Template == nil {
+		return req, nil
+	}
+	if s.store == nil {
+		return nil, &RequestError{Err: errTemplatesDisabled}
+	}
+	tmpl, err := s.store.Get(req.Template.ID, req.Template.Version)
+	if err != nil {
+		return nil, &RequestError{Err: err}
+	}
+	messages, err := tmpl.Render(prompts.NewData(requestmeta.FromContext(ctx), req.Model, req.Template.Variables))
+	if err != nil {
+		return nil, &RequestError{Err: err}
+	}
+
+	rendered := *req
+	rendered.Messages = append(messages, req.Messages...)
+	ref := *req.Template
+	ref.Version = tmpl.Version
+	ref.Rendered = len(messages)
+	rendered.Template = &ref
+	requestmeta.SetResponseHeader(ctx, TemplateHeader, tmpl.ID+"@"+strconv.Itoa(tmpl.Version))
+	return &rendered, nil
+}
+
+type systemPromptService struct {
+	next     LLMService
+	policies []SystemPromptPolicy
+	logger   *slog.Logger
+}
+
+// NewSystemPromptService wraps next so policies can prepend or enforce a
+// system prompt. It sits inside the session layer, so the prompt applies
+// to the whole conversation and is never stored with it. The first
+// matching policy applies.
+func NewSystemPromptService(next LLMService, policies []SystemPromptPolicy, logger *slog.Logger) LLMService {
+	return &systemPromptService{
+		next:     next,
+		policies: policies,
+		logger:   logger,
+	}
+}
+
+func (s *systemPromptService) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	applied, err := s.apply(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return s.next.ChatCompletion(ctx, applied)
+}
+
+func (s *systemPromptService) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	applied, err := s.apply(ctx, req)
+	if err != nil {
+		errCh := make(chan error, 1)
+		errCh <- err
+		close(errCh)
+		return nil, errCh
+	}
+	return s.next.ChatCompletionStream(ctx, applied)
+}
+
+// apply returns a copy of req with the matching policy's system prompt.
+// The prompt can use the variables of the template the request named.
+func (s *systemPromptService) apply(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionRequest, error) {
+	meta := requestmeta.FromContext(ctx)
+	policy := s.policyFor(meta, req.Model)
+	if policy == nil {
+		return req, nil
+	}
+
+	var vars map[string]string
+	if req.Template != nil {
+		vars = req.Template.Variables
+	}
+	content, err := prompts.Execute(policy.Content, prompts.NewData(meta, req.Model, vars))
+	if err != nil {
+		return nil, &RequestError{Err: err}
+	}
+
+	applied := *req
+	applied.Messages = []models.Message{{Role: "system", Content: content}}
+	for _, msg := range req.Messages {
+		if policy.Mode == SystemPromptEnforce && msg.Role == "system" {
+			continue
+		}
+		applied.Messages = append(applied.Messages, msg)
+	}
+	s.logger.DebugContext(ctx, "applied system prompt policy", slog.String("mode", policy.Mode))
+	return &applied, nil
+}
+
+func (s *systemPromptService) policyFor(meta requestmeta.Meta, model string) *SystemPromptPolicy {
+	for i := range s.policies {
+		if s.policies[i].Matches(meta, model) {
+			return &s.policies[i]
+		}
+	}
+	return nil
+}
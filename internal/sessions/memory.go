@@ -0,0 +1,67 @@
+package sessions
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+// MemoryStore keeps sessions in process memory; they are lost on restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]*Session)}
+}
+
+func (m *MemoryStore) Create(ctx context.Context, s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *s
+	stored.Messages = append([]models.Message(nil), s.Messages...)
+	m.sessions[s.ID] = &stored
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, key, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.Key != key {
+		return nil, ErrNotFound
+	}
+	c := *s
+	c.Messages = append([]models.Message(nil), s.Messages...)
+	return &c, nil
+}
+
+func (m *MemoryStore) Append(ctx context.Context, key, id string, messages ...models.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.Key != key {
+		return ErrNotFound
+	}
+	s.Messages = append(s.Messages, messages...)
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, key, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.Key != key {
+		return ErrNotFound
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,53 @@
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+// Session is a stored conversation owned by one client key.
+type Session struct {
+	ID        string            `json:"id"`
+	Key       string            `json:"-"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Messages  []models.Message  `json:"messages"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Store persists sessions. Get and Append only find sessions owned by key,
+// so one client cannot read or extend another's conversation.
+type Store interface {
+	Create(ctx context.Context, s *Session) error
+	Get(ctx context.Context, key, id string) (*Session, error)
+	Append(ctx context.Context, key, id string, messages ...models.Message) error
+	Delete(ctx context.Context, key, id string) error
+	Close() error
+}
+
+// OpenStore opens the store named by backend: "memory" or "sqlite" (a
+// database file at path).
+func OpenStore(backend, path string) (Store, error) {
+	switch backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "sqlite":
+		return NewSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("unknown session store: %s", backend)
+	}
+}
+
+func NewID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "sess_" + hex.EncodeToString(b)
+}
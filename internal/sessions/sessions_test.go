@@ -0,0 +1,108 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/llm-router/internal/models"
+)
+
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+	sqlite, err := NewSQLiteStore(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlite.Close() })
+	return map[string]Store{"memory": NewMemoryStore(), "sqlite": sqlite}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			first := models.Message{Role: "system", Content: "be brief"}
+			if err := store.Create(ctx, &Session{
+				ID:        "sess-1",
+				Key:       "key-a",
+				Metadata:  map[string]string{"user": "u1"},
+				Messages:  []models.Message{first},
+				CreatedAt: now,
+				UpdatedAt: now,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			turn := []models.Message{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}}
+			if err := store.Append(ctx, "key-a", "sess-1", turn...); err != nil {
+				t.Fatal(err)
+			}
+			s, err := store.Get(ctx, "key-a", "sess-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := append([]models.Message{first}, turn...); !reflect.DeepEqual(s.Messages, want) {
+				t.Errorf("messages = %v, want %v", s.Messages, want)
+			}
+			if s.Metadata["user"] != "u1" {
+				t.Errorf("metadata = %v", s.Metadata)
+			}
+
+			// Changing what Get returned does not change the stored session.
+			s.Messages[0].Content = "changed"
+			if again, _ := store.Get(ctx, "key-a", "sess-1"); again.Messages[0].Content != "be brief" {
+				t.Error("stored session changed through a returned copy")
+			}
+
+			if err := store.Delete(ctx, "key-a", "sess-1"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(ctx, "key-a", "sess-1"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestStoreOwnership(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			if err := store.Create(ctx, &Session{ID: "sess-1", Key: "key-a", CreatedAt: now, UpdatedAt: now}); err != nil {
+				t.Fatal(err)
+			}
+
+			ops := map[string]func(key string) error{
+				"get": func(key string) error {
+					_, err := store.Get(ctx, key, "sess-1")
+					return err
+				},
+				"append": func(key string) error {
+					return store.Append(ctx, key, "sess-1", models.Message{Role: "user", Content: "hi"})
+				},
+				"delete": func(key string) error {
+					return store.Delete(ctx, key, "sess-1")
+				},
+			}
+			for op, run := range ops {
+				if err := run("key-b"); !errors.Is(err, ErrNotFound) {
+					t.Errorf("%s by another key = %v, want ErrNotFound", op, err)
+				}
+			}
+
+			s, err := store.Get(ctx, "key-a", "sess-1")
+			if err != nil {
+				t.Fatalf("owner lost the session: %v", err)
+			}
+			if len(s.Messages) != 0 {
+				t.Errorf("another key appended %v", s.Messages)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+const createSessionTables = `
+CREATE TABLE IF NOT EXISTS sessions (
+	id         TEXT PRIMARY KEY,
+	key        TEXT NOT NULL,
+	metadata   TEXT,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS session_messages (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_id TEXT NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+	message    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS session_messages_session_id ON session_messages (session_id, id);
+`
+
+// SQLiteStore keeps sessions in a local SQLite database, one row per message
+// with the message stored as JSON.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+	if _, err := db.Exec(createSessionTables); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create session tables: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Create(ctx context.Context, session *Session) error {
+	metadata, err := json.Marshal(session.Metadata)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO sessions (id, key, metadata, created_at, updated_at) VALUES (?, ?, ?, ?, ?)`,
+		session.ID, session.Key, string(metadata),
+		session.CreatedAt.UTC().Format(time.RFC3339Nano), session.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	); err != nil {
+		return err
+	}
+	if err := insertMessages(ctx, tx, session.ID, session.Messages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Get(ctx context.Context, key, id string) (*Session, error) {
+	session := &Session{ID: id, Key: key}
+	var metadata, createdAt, updatedAt string
+	err := s.db.QueryRowContext(ctx,
+		`SELECT metadata, created_at, updated_at FROM sessions WHERE id = ? AND key = ?`, id, key,
+	).Scan(&metadata, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(metadata), &session.Metadata); err != nil {
+		return nil, err
+	}
+	session.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+	session.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+
+	rows, err := s.db.QueryContext(ctx, `SELECT message FROM session_messages WHERE session_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	session.Messages = []models.Message{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var msg models.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, err
+		}
+		session.Messages = append(session.Messages, msg)
+	}
+	return session, rows.Err()
+}
+
+func (s *SQLiteStore) Append(ctx context.Context, key, id string, messages ...models.Message) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `UPDATE sessions SET updated_at = ? WHERE id = ? AND key = ?`,
+		time.Now().UTC().Format(time.RFC3339Nano), id, key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	if err := insertMessages(ctx, tx, id, messages); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Delete(ctx context.Context, key, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = ? AND key = ?`, id, key)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func insertMessages(ctx context.Context, tx *sql.Tx, sessionID string, messages []models.Message) error {
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO session_messages (session_id, message) VALUES (?, ?)`, sessionID, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package shadow
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/requestmeta"
+)
+
+// CompleteFunc runs a shadow request.
+type CompleteFunc func(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error)
+
+type Options struct {
+	// MaxInFlight bounds concurrent shadow requests; comparisons beyond it
+	// are dropped rather than queued.
+	MaxInFlight int
+	Timeout     time.Duration
+}
+
+// Mirror runs shadow requests in the background and saves each next to the
+// production answer it mirrors.
+type Mirror struct {
+	store    *Store
+	complete CompleteFunc
+	opts     Options
+	logger   *slog.Logger
+
+	slots chan struct{}
+	wg    sync.WaitGroup
+}
+
+func NewMirror(store *Store, complete CompleteFunc, opts Options, logger *slog.Logger) *Mirror {
+	return &Mirror{
+		store:    store,
+		complete: complete,
+		opts:     opts,
+		logger:   logger,
+		slots:    make(chan struct{}, max(opts.MaxInFlight, 1)),
+	}
+}
+
+// Submit sends req to c.Shadow.Model and saves the comparison once it
+// answers. It never blocks and never fails the caller.
+func (m *Mirror) Submit(ctx context.Context, req *models.ChatCompletionRequest, c *Comparison) {
+	select {
+	case m.slots <- struct{}{}:
+	default:
+		m.logger.DebugContext(ctx, "shadow request dropped, too many in flight")
+		return
+	}
+
+	shadowReq := *req
+	shadowReq.Model = c.Shadow.Model
+	shadowReq.Stream = false
+	shadowReq.StreamOptions = nil
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer func() { <-m.slots }()
+
+		// The reply has already been sent, so the shadow call must not set
+		// response headers.
+		meta, _ := requestmeta.Lookup(ctx)
+		meta = meta.Shadow()
+		meta.ResponseHeader = nil
+		ctx, cancel := context.WithTimeout(requestmeta.WithMeta(context.WithoutCancel(ctx), meta), m.opts.Timeout)
+		defer cancel()
+
+		start := time.Now()
+		resp, err := m.complete(ctx, &shadowReq)
+		c.Shadow.Latency = time.Since(start)
+		if err != nil {
+			c.Shadow.Error = err.Error()
+		} else {
+			c.Shadow.SetResponse(resp)
+		}
+
+		if err := m.store.Save(context.WithoutCancel(ctx), c); err != nil {
+			m.logger.ErrorContext(ctx, "failed to save shadow comparison", slog.Any("error", err))
+		}
+	}()
+}
+
+// Close waits for shadow requests in flight, then closes the store.
+func (m *Mirror) Close() error {
+	m.wg.Wait()
+	return m.store.Close()
+}
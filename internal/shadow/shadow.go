@@ -0,0 +1,112 @@
+package shadow
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/llm-router/internal/accounting"
+	"github.com/llm-router/internal/models"
+	_ "modernc.org/sqlite"
+)
+
+const createComparisonTable = `
+CREATE TABLE IF NOT EXISTS comparisons (
+	id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+	time               TEXT NOT NULL,
+	request_id         TEXT NOT NULL,
+	key                TEXT NOT NULL,
+	route              TEXT NOT NULL,
+	request            TEXT NOT NULL,
+	primary_model      TEXT NOT NULL,
+	primary_output     TEXT,
+	primary_error      TEXT,
+	primary_latency_ms INTEGER NOT NULL,
+	primary_tokens     INTEGER NOT NULL,
+	primary_cost       REAL NOT NULL,
+	shadow_model       TEXT NOT NULL,
+	shadow_output      TEXT,
+	shadow_error       TEXT,
+	shadow_latency_ms  INTEGER NOT NULL,
+	shadow_tokens      INTEGER NOT NULL,
+	shadow_cost        REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS comparisons_time ON comparisons (time);
+`
+
+// Result is one model's answer to a mirrored request.
+type Result struct {
+	Model   string
+	Output  string
+	Error   string
+	Latency time.Duration
+	Usage   models.Usage
+	Cost    float64
+}
+
+// SetResponse fills r from a successful response.
+func (r *Result) SetResponse(resp *models.ChatCompletionResponse) {
+	r.Model = resp.Model
+	if len(resp.Choices) > 0 {
+		r.Output = resp.Choices[0].Message.Content
+	}
+	r.Usage = resp.Usage
+	r.Cost = accounting.Cost(resp.Model, resp.Usage)
+}
+
+// Comparison holds the production and shadow answers to the same request.
+type Comparison struct {
+	Time      time.Time
+	RequestID string
+	Key       string
+	Route     string
+	Request   *models.ChatCompletionRequest
+	Primary   Result
+	Shadow    Result
+}
+
+// Store keeps comparisons in SQLite with one column per field, so they can
+// be queried directly for offline evaluation.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shadow database: %w", err)
+	}
+	if _, err := db.Exec(createComparisonTable); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create shadow tables: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Save(ctx context.Context, c *Comparison) error {
+	request, err := json.Marshal(c.Request)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO comparisons (
+			time, request_id, key, route, request,
+			primary_model, primary_output, primary_error, primary_latency_ms, primary_tokens, primary_cost,
+			shadow_model, shadow_output, shadow_error, shadow_latency_ms, shadow_tokens, shadow_cost
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.Time.UTC().Format(time.RFC3339Nano), c.RequestID, c.Key, c.Route, string(request),
+		c.Primary.Model, c.Primary.Output, nullString(c.Primary.Error), c.Primary.Latency.Milliseconds(), c.Primary.Usage.TotalTokens, c.Primary.Cost,
+		c.Shadow.Model, c.Shadow.Output, nullString(c.Shadow.Error), c.Shadow.Latency.Milliseconds(), c.Shadow.Usage.TotalTokens, c.Shadow.Cost,
+	)
+	return err
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
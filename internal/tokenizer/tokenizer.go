@@ -0,0 +1,126 @@
+package tokenizer
+
+import (
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+
+	"github.com/llm-router/internal/models"
+)
+
+// Encodings shipped with the router.
+const (
+	O200kBase  = "o200k_base"
+	Cl100kBase = "cl100k_base"
+)
+
+// Tokenizer counts tokens for one model family. Exact is false when the
+// count approximates a tokenizer the router does not have, such as
+// Anthropic's.
+type Tokenizer struct {
+	Encoding string
+	Exact    bool
+	// scale adjusts approximate counts for the target model family.
+	scale float64
+}
+
+// families maps model-name prefixes to tokenizers, longest prefix first
+// wins. Claude models are approximated with cl100k_base, which counts
+// roughly 10-15% fewer tokens than Anthropic's tokenizer on English text.
+var families = map[string]Tokenizer{
+	"gpt-4o":        {Encoding: O200kBase, Exact: true},
+	"gpt-4.1":       {Encoding: O200kBase, Exact: true},
+	"o1":            {Encoding: O200kBase, Exact: true},
+	"o3":            {Encoding: O200kBase, Exact: true},
+	"o4":            {Encoding: O200kBase, Exact: true},
+	"gpt-4":         {Encoding: Cl100kBase, Exact: true},
+	"gpt-3.5-turbo": {Encoding: Cl100kBase, Exact: true},
+	"claude":        {Encoding: Cl100kBase, scale: 1.15},
+}
+
+// fallback counts models from other families with plain cl100k_base. There
+// is no scale known to suit them all, so the count is only marked inexact.
+var fallback = Tokenizer{Encoding: Cl100kBase}
+
+func init() {
+	// Use the encodings embedded in the binary rather than downloading them.
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+func ForModel(model string) Tokenizer {
+	model = strings.ToLower(model)
+	var best string
+	for prefix := range families {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return fallback
+	}
+	return families[best]
+}
+
+var (
+	mu        sync.Mutex
+	encodings = make(map[string]*tiktoken.Tiktoken)
+)
+
+// encoding loads an encoding on first use; loading takes a noticeable
+// fraction of a second.
+func encoding(name string) *tiktoken.Tiktoken {
+	mu.Lock()
+	defer mu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		slog.Error("failed to load tokenizer encoding", slog.String("encoding", name), slog.Any("error", err))
+	}
+	encodings[name] = enc
+	return enc
+}
+
+// Count returns the number of tokens in text. If the encoding cannot be
+// loaded it falls back to roughly four characters per token.
+func (t Tokenizer) Count(text string) int64 {
+	if text == "" {
+		return 0
+	}
+	enc := encoding(t.Encoding)
+	if enc == nil {
+		return int64(len(text)+3) / 4
+	}
+	n := float64(len(enc.EncodeOrdinary(text)))
+	if t.scale > 0 {
+		n = math.Ceil(n * t.scale)
+	}
+	return int64(n)
+}
+
+// Per-message framing in OpenAI's chat format: each message costs a few
+// tokens for its role and delimiters, and the reply is primed with a few
+// more.
+const (
+	tokensPerMessage = 3
+	tokensPerName    = 1
+	tokensForReply   = 3
+)
+
+// CountMessages returns the prompt tokens a chat request's messages use,
+// including chat framing.
+func (t Tokenizer) CountMessages(messages []models.Message) int64 {
+	total := int64(tokensForReply)
+	for _, msg := range messages {
+		total += tokensPerMessage + t.Count(msg.Role) + t.Count(msg.Content)
+		if msg.Name != "" {
+			total += tokensPerName + t.Count(msg.Name)
+		}
+	}
+	return total
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/llm-router/internal/models"
+	"github.com/llm-router/internal/providers"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// GenAI semantic convention attribute keys.
+const (
+	attrSystem        = "gen_ai.system"
+	attrOperation     = "gen_ai.operation.name"
+	attrRequestModel  = "gen_ai.request.model"
+	attrMaxTokens     = "gen_ai.request.max_tokens"
+	attrTemperature   = "gen_ai.request.temperature"
+	attrTopP          = "gen_ai.request.top_p"
+	attrResponseID    = "gen_ai.response.id"
+	attrResponseModel = "gen_ai.response.model"
+	attrFinishReasons = "gen_ai.response.finish_reasons"
+	attrInputTokens   = "gen_ai.usage.input_tokens"
+	attrOutputTokens  = "gen_ai.usage.output_tokens"
+	attrStream        = "llm_router.stream"
+	attrErrorClass    = "error.type"
+	operationChat     = "chat"
+)
+
+type tracedProvider struct {
+	providers.Provider
+}
+
+// TraceProvider wraps p so every upstream call gets its own client span.
+func TraceProvider(p providers.Provider) providers.Provider {
+	return &tracedProvider{Provider: p}
+}
+
+func (p *tracedProvider) start(ctx context.Context, req *models.ChatCompletionRequest) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{
+		attribute.String(attrSystem, p.Name()),
+		attribute.String(attrOperation, operationChat),
+		attribute.String(attrRequestModel, req.Model),
+		attribute.Bool(attrStream, req.Stream),
+	}
+	if req.MaxTokens != nil {
+		attrs = append(attrs, attribute.Int64(attrMaxTokens, *req.MaxTokens))
+	}
+	if req.Temperature != nil {
+		attrs = append(attrs, attribute.Float64(attrTemperature, *req.Temperature))
+	}
+	if req.TopP != nil {
+		attrs = append(attrs, attribute.Float64(attrTopP, *req.TopP))
+	}
+
+	return Tracer().Start(ctx, operationChat+" "+req.Model,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+func (p *tracedProvider) ChatCompletion(ctx context.Context, req *models.ChatCompletionRequest) (*models.ChatCompletionResponse, error) {
+	ctx, span := p.start(ctx, req)
+	defer span.End()
+
+	resp, err := p.Provider.ChatCompletion(ctx, req)
+	if err != nil {
+		span.SetAttributes(attribute.String(attrErrorClass, providers.ErrorClass(err)))
+		RecordError(span, err)
+		return nil, err
+	}
+
+	reasons := make([]string, 0, len(resp.Choices))
+	for _, choice := range resp.Choices {
+		reasons = append(reasons, choice.FinishReason)
+	}
+	span.SetAttributes(
+		attribute.String(attrResponseID, resp.ID),
+		attribute.String(attrResponseModel, resp.Model),
+		attribute.StringSlice(attrFinishReasons, reasons),
+		attribute.Int64(attrInputTokens, resp.Usage.PromptTokens),
+		attribute.Int64(attrOutputTokens, resp.Usage.CompletionTokens),
+	)
+	return resp, nil
+}
+
+func (p *tracedProvider) ChatCompletionStream(ctx context.Context, req *models.ChatCompletionRequest) (<-chan *models.ChatCompletionChunk, <-chan error) {
+	ctx, span := p.start(ctx, req)
+	chunkCh, errCh := p.Provider.ChatCompletionStream(ctx, req)
+
+	var reasons []string
+	responseSeen := false
+	return providers.Relay(ctx, chunkCh, errCh, providers.StreamHooks{
+		OnChunk: func(chunk *models.ChatCompletionChunk) *models.ChatCompletionChunk {
+			if !responseSeen && chunk.ID != "" {
+				responseSeen = true
+				span.SetAttributes(
+					attribute.String(attrResponseID, chunk.ID),
+					attribute.String(attrResponseModel, chunk.Model),
+				)
+			}
+			for _, choice := range chunk.Choices {
+				if choice.FinishReason != nil {
+					reasons = append(reasons, *choice.FinishReason)
+				}
+			}
+			if chunk.Usage != nil {
+				span.SetAttributes(
+					attribute.Int64(attrInputTokens, chunk.Usage.PromptTokens),
+					attribute.Int64(attrOutputTokens, chunk.Usage.CompletionTokens),
+				)
+			}
+			return chunk
+		},
+		OnError: func(err error) {
+			span.SetAttributes(attribute.String(attrErrorClass, providers.ErrorClass(err)))
+			RecordError(span, err)
+		},
+		OnDone: func() {
+			span.SetAttributes(attribute.StringSlice(attrFinishReasons, reasons))
+			span.End()
+		},
+	})
+}